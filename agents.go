@@ -10,9 +10,19 @@ import (
 	"google.golang.org/genai"
 )
 
-func GetAgent(apiKey, agentName string) (agent.Agent, error) {
+func GetAgent(apiKey, agentName string, generateConfig *genai.GenerateContentConfig) (agent.Agent, error) {
+	return newAgent(apiKey, "gemini-2.5-pro", agentName, "Analyze Resume", prompt(), generateConfig)
+}
+
+// GetKnockoutAgent returns the agent used for model-checked knockout rules.
+// It runs on a cheaper model than the full analysis.
+func GetKnockoutAgent(apiKey, modelName, agentName string) (agent.Agent, error) {
+	return newAgent(apiKey, modelName, agentName, "Screen resume against mandatory requirements", knockoutPrompt(), nil)
+}
+
+func newAgent(apiKey, modelName, agentName, description, instruction string, generateConfig *genai.GenerateContentConfig) (agent.Agent, error) {
 	ctx := context.Background()
-	model, err := gemini.NewModel(ctx, "gemini-2.5-pro", &genai.ClientConfig{
+	model, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{
 		APIKey: apiKey,
 	})
 
@@ -23,8 +33,10 @@ func GetAgent(apiKey, agentName string) (agent.Agent, error) {
 	customAgent, err := llmagent.New(llmagent.Config{
 		Name:        agentName,
 		Model:       model,
-		Description: "Analyze Resume",
-		Instruction: prompt(),
+		Description: description,
+		Instruction: instruction,
+
+		GenerateContentConfig: generateConfig,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %v", err)
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ArchiveLimits bound the work done expanding an uploaded ZIP of resumes.
+type ArchiveLimits struct {
+	// MaxFiles counts files across nested archives.
+	MaxFiles int
+	// MaxBytes is the total uncompressed size read out of the archive.
+	MaxBytes int64
+	// MaxDepth is how many archives deep files are read; 1 reads only the
+	// uploaded archive itself.
+	MaxDepth int
+}
+
+func loadArchiveLimits(maxFiles, maxBytes, maxDepth string) (ArchiveLimits, error) {
+	limits := ArchiveLimits{MaxFiles: 200, MaxBytes: 256 << 20, MaxDepth: 2}
+	if maxFiles != "" {
+		n, err := strconv.Atoi(maxFiles)
+		if err != nil || n < 1 {
+			return limits, fmt.Errorf("invalid max files %q", maxFiles)
+		}
+		limits.MaxFiles = n
+	}
+	if maxBytes != "" {
+		n, err := strconv.ParseInt(maxBytes, 10, 64)
+		if err != nil || n < 1 {
+			return limits, fmt.Errorf("invalid max bytes %q", maxBytes)
+		}
+		limits.MaxBytes = n
+	}
+	if maxDepth != "" {
+		n, err := strconv.Atoi(maxDepth)
+		if err != nil || n < 1 {
+			return limits, fmt.Errorf("invalid max depth %q", maxDepth)
+		}
+		limits.MaxDepth = n
+	}
+	return limits, nil
+}
+
+// archiveEntry is one file read out of an archive, or the reason it (or the
+// rest of the archive) couldn't be read. Path is the full path through any
+// nested archives, e.g. "batch2.zip/cv/jane.pdf".
+type archiveEntry struct {
+	Path string
+	// Mime is guessed from the extension; content sniffing has the final
+	// say.
+	Mime string
+	Data []byte
+	Err  error
+}
+
+// archiveEntryJSON carries an archiveEntry out of the sandbox, with its
+// error as text.
+type archiveEntryJSON struct {
+	Path  string `json:"path"`
+	Mime  string `json:"mime,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func (e archiveEntry) MarshalJSON() ([]byte, error) {
+	out := archiveEntryJSON{Path: e.Path, Mime: e.Mime, Data: e.Data}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+func (e *archiveEntry) UnmarshalJSON(data []byte) error {
+	var in archiveEntryJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = archiveEntry{Path: in.Path, Mime: in.Mime, Data: in.Data}
+	if in.Error != "" {
+		e.Err = errors.New(in.Error)
+	}
+	return nil
+}
+
+// archiveBudget is shared across nested archives.
+type archiveBudget struct {
+	limits ArchiveLimits
+	files  int
+	bytes  int64
+	// stopped is set once a limit is hit, ending the expansion
+	stopped bool
+}
+
+// expandArchive reads the files out of a ZIP archive, expanding nested ZIPs
+// up to the depth limit. Uncompressed sizes in the archive's headers aren't
+// trusted: reads are cut off at the remaining byte budget.
+func expandArchive(data []byte, limits ArchiveLimits) ([]archiveEntry, error) {
+	budget := &archiveBudget{limits: limits}
+	return budget.expand(data, "", 1)
+}
+
+func (b *archiveBudget) expand(data []byte, prefix string, depth int) ([]archiveEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var entries []archiveEntry
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(f.Name, `\`, "/")), "/")
+		base := path.Base(name)
+		// directories, macOS resource forks and hidden files
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		entryPath := prefix + name
+
+		if b.files >= b.limits.MaxFiles {
+			b.stopped = true
+			entries = append(entries, archiveEntry{Path: entryPath, Err: fmt.Errorf("archive has more than %d files, the rest were skipped", b.limits.MaxFiles)})
+			return entries, nil
+		}
+		b.files++
+
+		if f.Flags&0x1 != 0 {
+			entries = append(entries, archiveEntry{Path: entryPath, Err: fmt.Errorf("file is encrypted")})
+			continue
+		}
+		content, err := b.read(f)
+		if err != nil {
+			entries = append(entries, archiveEntry{Path: entryPath, Err: err})
+			if b.bytes >= b.limits.MaxBytes {
+				b.stopped = true
+				return entries, nil
+			}
+			continue
+		}
+
+		if sniffMime(content) == mimeZip {
+			if depth >= b.limits.MaxDepth {
+				entries = append(entries, archiveEntry{Path: entryPath, Err: fmt.Errorf("archive nested more than %d deep", b.limits.MaxDepth)})
+				continue
+			}
+			nested, err := b.expand(content, entryPath+"/", depth+1)
+			if err != nil {
+				entries = append(entries, archiveEntry{Path: entryPath, Err: err})
+				continue
+			}
+			entries = append(entries, nested...)
+			if b.stopped {
+				return entries, nil
+			}
+			continue
+		}
+		entries = append(entries, archiveEntry{Path: entryPath, Mime: mime.TypeByExtension(path.Ext(base)), Data: content})
+	}
+	return entries, nil
+}
+
+// read decompresses one file within the remaining byte budget.
+func (b *archiveBudget) read(f *zip.File) ([]byte, error) {
+	remaining := b.limits.MaxBytes - b.bytes
+	tooLarge := fmt.Errorf("archive is larger than %d bytes uncompressed, the rest was skipped", b.limits.MaxBytes)
+	if f.UncompressedSize64 > uint64(remaining) {
+		b.bytes = b.limits.MaxBytes
+		return nil, tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+	b.bytes += int64(len(content))
+	if int64(len(content)) > remaining {
+		return nil, tooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress file: %w", err)
+	}
+	return content, nil
+}
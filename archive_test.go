@@ -0,0 +1,261 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type zipFile struct {
+	name string
+	data string
+	// flags are set on the file header, e.g. 0x1 for encrypted
+	flags uint16
+}
+
+// zipOf builds a stored ZIP with the files in order.
+func zipOf(t *testing.T, files ...zipFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Store, Flags: f.flags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// describeEntries summarises entries as "path=data" or "path: error".
+func describeEntries(entries []archiveEntry) []string {
+	var out []string
+	for _, e := range entries {
+		if e.Err != nil {
+			out = append(out, e.Path+": "+e.Err.Error())
+		} else {
+			out = append(out, e.Path+"="+string(e.Data))
+		}
+	}
+	return out
+}
+
+var testArchiveLimits = ArchiveLimits{MaxFiles: 10, MaxBytes: 1 << 20, MaxDepth: 2}
+
+func TestExpandArchive(t *testing.T) {
+	data := zipOf(t,
+		zipFile{name: "cv/"},
+		zipFile{name: "cv/jane.pdf", data: "jane"},
+		zipFile{name: `cv\john.pdf`, data: "john"},
+		zipFile{name: "../escape.pdf", data: "escape"},
+		zipFile{name: "__MACOSX/cv/._jane.pdf", data: "fork"},
+		zipFile{name: ".DS_Store", data: "finder"},
+		zipFile{name: "cv/.hidden.pdf", data: "hidden"},
+	)
+	entries, err := expandArchive(data, testArchiveLimits)
+	if err != nil {
+		t.Fatalf("expandArchive: %v", err)
+	}
+	want := []string{"cv/jane.pdf=jane", "cv/john.pdf=john", "escape.pdf=escape"}
+	if got := describeEntries(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if entries[0].Mime != mimePDF {
+		t.Errorf("Mime = %q, want %q", entries[0].Mime, mimePDF)
+	}
+}
+
+func TestExpandArchiveLimits(t *testing.T) {
+	limits := func(files int, bytes int64, depth int) ArchiveLimits {
+		return ArchiveLimits{MaxFiles: files, MaxBytes: bytes, MaxDepth: depth}
+	}
+	tests := []struct {
+		name   string
+		data   func(t *testing.T) []byte
+		limits ArchiveLimits
+		want   []string
+	}{
+		{
+			name: "file count",
+			data: func(t *testing.T) []byte {
+				return zipOf(t, zipFile{name: "a.pdf", data: "a"}, zipFile{name: "b.pdf", data: "b"}, zipFile{name: "c.pdf", data: "c"}, zipFile{name: "d.pdf", data: "d"})
+			},
+			limits: limits(2, 1<<20, 2),
+			want:   []string{"a.pdf=a", "b.pdf=b", "c.pdf: archive has more than 2 files, the rest were skipped"},
+		},
+		{
+			name: "file count across nested archives",
+			data: func(t *testing.T) []byte {
+				inner := zipOf(t, zipFile{name: "b.pdf", data: "b"}, zipFile{name: "c.pdf", data: "c"})
+				return zipOf(t, zipFile{name: "a.pdf", data: "a"}, zipFile{name: "inner.zip", data: string(inner)}, zipFile{name: "d.pdf", data: "d"})
+			},
+			// the nested archive counts as a file too
+			limits: limits(3, 1<<20, 2),
+			want:   []string{"a.pdf=a", "inner.zip/b.pdf=b", "inner.zip/c.pdf: archive has more than 3 files, the rest were skipped"},
+		},
+		{
+			name: "byte budget",
+			data: func(t *testing.T) []byte {
+				chunk := strings.Repeat("x", 40)
+				return zipOf(t, zipFile{name: "a.pdf", data: chunk}, zipFile{name: "b.pdf", data: chunk}, zipFile{name: "c.pdf", data: chunk}, zipFile{name: "d.pdf", data: "d"})
+			},
+			limits: limits(10, 100, 2),
+			want: []string{
+				"a.pdf=" + strings.Repeat("x", 40),
+				"b.pdf=" + strings.Repeat("x", 40),
+				"c.pdf: archive is larger than 100 bytes uncompressed, the rest was skipped",
+			},
+		},
+		{
+			// zip.Reader stops at the declared size, well inside the budget
+			name:   "understated uncompressed size",
+			data:   lyingZip,
+			limits: limits(10, 100, 2),
+			want:   []string{"bomb.pdf: failed to decompress file: zip: not a valid zip file", "next.pdf=next"},
+		},
+		{
+			name: "depth",
+			data: func(t *testing.T) []byte {
+				level3 := zipOf(t, zipFile{name: "deep.pdf", data: "deep"})
+				level2 := zipOf(t, zipFile{name: "level3.zip", data: string(level3)}, zipFile{name: "shallow.pdf", data: "shallow"})
+				return zipOf(t, zipFile{name: "level2.zip", data: string(level2)}, zipFile{name: "top.pdf", data: "top"})
+			},
+			limits: limits(10, 1<<20, 2),
+			want:   []string{"level2.zip/level3.zip: archive nested more than 2 deep", "level2.zip/shallow.pdf=shallow", "top.pdf=top"},
+		},
+		{
+			name: "depth within limit",
+			data: func(t *testing.T) []byte {
+				level3 := zipOf(t, zipFile{name: "deep.pdf", data: "deep"})
+				level2 := zipOf(t, zipFile{name: "level3.zip", data: string(level3)})
+				return zipOf(t, zipFile{name: "level2.zip", data: string(level2)})
+			},
+			limits: limits(10, 1<<20, 3),
+			want:   []string{"level2.zip/level3.zip/deep.pdf=deep"},
+		},
+		{
+			name: "encrypted entry",
+			data: func(t *testing.T) []byte {
+				return zipOf(t, zipFile{name: "locked.pdf", data: "ciphertext", flags: 0x1}, zipFile{name: "open.pdf", data: "open"})
+			},
+			limits: limits(10, 1<<20, 2),
+			want:   []string{"locked.pdf: file is encrypted", "open.pdf=open"},
+		},
+		{
+			name: "corrupt nested archive",
+			data: func(t *testing.T) []byte {
+				return zipOf(t, zipFile{name: "broken.zip", data: "PK\x03\x04 truncated"}, zipFile{name: "ok.pdf", data: "ok"})
+			},
+			limits: limits(10, 1<<20, 2),
+			want:   []string{"broken.zip: invalid zip archive: zip: not a valid zip file", "ok.pdf=ok"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := expandArchive(tt.data(t), tt.limits)
+			if err != nil {
+				t.Fatalf("expandArchive: %v", err)
+			}
+			if got := describeEntries(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// lyingZip holds a deflated file whose header claims it's 10 bytes long,
+// followed by an honest one.
+func lyingZip(t *testing.T) []byte {
+	t.Helper()
+	content := bytes.Repeat([]byte("A"), 1000)
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "bomb.pdf",
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+	w, err = zw.CreateHeader(&zip.FileHeader{Name: "next.pdf", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("next"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExpandArchiveRejectsNonZip(t *testing.T) {
+	if _, err := expandArchive([]byte("%PDF-1.7 not an archive"), testArchiveLimits); err == nil {
+		t.Error("expandArchive accepted a non-zip upload")
+	}
+}
+
+func TestArchiveEntryJSON(t *testing.T) {
+	entries, err := expandArchive(zipOf(t, zipFile{name: "locked.pdf", flags: 0x1}, zipFile{name: "jane.pdf", data: "%PDF"}), testArchiveLimits)
+	if err != nil {
+		t.Fatalf("expandArchive: %v", err)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded []archiveEntry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got, want := describeEntries(decoded), describeEntries(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after round trip = %q, want %q", got, want)
+	}
+	if decoded[1].Mime != mimePDF {
+		t.Errorf("Mime after round trip = %q, want %q", decoded[1].Mime, mimePDF)
+	}
+}
+
+func TestLoadArchiveLimits(t *testing.T) {
+	tests := []struct {
+		files, bytes, depth string
+		want                ArchiveLimits
+		wantErr             bool
+	}{
+		{want: ArchiveLimits{MaxFiles: 200, MaxBytes: 256 << 20, MaxDepth: 2}},
+		{files: "5", bytes: "1024", depth: "1", want: ArchiveLimits{MaxFiles: 5, MaxBytes: 1024, MaxDepth: 1}},
+		{files: "0", wantErr: true},
+		{bytes: "-1", wantErr: true},
+		{depth: "deep", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := loadArchiveLimits(tt.files, tt.bytes, tt.depth)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("loadArchiveLimits(%q, %q, %q) error = %v, wantErr %v", tt.files, tt.bytes, tt.depth, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("loadArchiveLimits(%q, %q, %q) = %+v, want %+v", tt.files, tt.bytes, tt.depth, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Compound File Binary (OLE2) is the container behind legacy Office files:
+// a small FAT file system whose "streams" hold the actual document parts.
+
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSect   = 0xFFFFFFFF
+	cfbHeaderSize = 512
+	cfbDirEntry   = 128
+
+	cfbTypeStorage = 1
+	cfbTypeStream  = 2
+	cfbTypeRoot    = 5
+)
+
+var errCorruptCFB = errors.New("corrupt compound file")
+
+type cfbEntry struct {
+	name  string
+	typ   byte
+	start uint32
+	size  uint64
+	// sibling and child ids of the directory red-black tree
+	left, right, child uint32
+}
+
+// cfbFile is a parsed compound file held in memory.
+type cfbFile struct {
+	data       []byte
+	sectorSize int
+	miniSize   int
+	miniCutoff uint64
+	// sectors is how many sectors the file actually has; no chain can be
+	// longer
+	sectors    int
+	fat        []uint32
+	miniFAT    []uint32
+	miniStream []byte
+	entries    []cfbEntry
+}
+
+func openCFB(data []byte) (*cfbFile, error) {
+	if len(data) < cfbHeaderSize || !strings.HasPrefix(string(data), string(oleMagic)) {
+		return nil, errCorruptCFB
+	}
+	le := binary.LittleEndian
+	shift := le.Uint16(data[0x1E:])
+	miniShift := le.Uint16(data[0x20:])
+	if shift != 9 && shift != 12 || miniShift != 6 {
+		return nil, fmt.Errorf("%w: unexpected sector size", errCorruptCFB)
+	}
+	f := &cfbFile{
+		data:       data,
+		sectorSize: 1 << shift,
+		miniSize:   1 << miniShift,
+		miniCutoff: uint64(le.Uint32(data[0x38:])),
+	}
+	// the last sector is sometimes truncated
+	f.sectors = (len(data) - cfbHeaderSize + f.sectorSize - 1) / f.sectorSize
+
+	// the DIFAT lists the sectors holding the FAT: 109 entries in the
+	// header, the rest in a chain of DIFAT sectors. Both are bounded by the
+	// file's sector count and may not repeat a sector, so a crafted file
+	// can't make the FAT larger than the file itself.
+	var fatSectors []uint32
+	isFAT := map[uint32]bool{}
+	addFAT := func(s uint32) error {
+		if s == cfbFreeSect {
+			return nil
+		}
+		if int64(s) >= int64(f.sectors) || isFAT[s] {
+			return fmt.Errorf("%w: bad FAT sector %d", errCorruptCFB, s)
+		}
+		isFAT[s] = true
+		fatSectors = append(fatSectors, s)
+		return nil
+	}
+	for i := range 109 {
+		if err := addFAT(le.Uint32(data[0x4C+4*i:])); err != nil {
+			return nil, err
+		}
+	}
+	next := le.Uint32(data[0x44:])
+	perSector := f.sectorSize/4 - 1
+	difat := map[uint32]bool{}
+	for next != cfbEndOfChain && next != cfbFreeSect {
+		if difat[next] || len(difat) >= f.sectors {
+			return nil, fmt.Errorf("%w: bad DIFAT chain", errCorruptCFB)
+		}
+		difat[next] = true
+		sector, err := f.sector(next)
+		if err != nil || len(sector) < f.sectorSize {
+			return nil, fmt.Errorf("%w: bad DIFAT chain", errCorruptCFB)
+		}
+		for i := range perSector {
+			if err := addFAT(le.Uint32(sector[4*i:])); err != nil {
+				return nil, err
+			}
+		}
+		next = le.Uint32(sector[4*perSector:])
+	}
+	for _, s := range fatSectors {
+		sector, err := f.sector(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad FAT sector", errCorruptCFB)
+		}
+		for i := 0; i+4 <= len(sector); i += 4 {
+			f.fat = append(f.fat, le.Uint32(sector[i:]))
+		}
+	}
+
+	dir, err := f.chain(le.Uint32(data[0x30:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad directory: %v", errCorruptCFB, err)
+	}
+	for i := 0; i+cfbDirEntry <= len(dir); i += cfbDirEntry {
+		raw := dir[i : i+cfbDirEntry]
+		nameLen := int(le.Uint16(raw[64:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		units := make([]uint16, 0, 32)
+		for j := 0; j+1 < nameLen; j += 2 {
+			if u := le.Uint16(raw[j:]); u != 0 {
+				units = append(units, u)
+			}
+		}
+		size := le.Uint64(raw[120:])
+		if f.sectorSize == 512 {
+			// version 3 files only define the low 32 bits
+			size &= 0xFFFFFFFF
+		}
+		f.entries = append(f.entries, cfbEntry{
+			name:  string(utf16.Decode(units)),
+			typ:   raw[66],
+			left:  le.Uint32(raw[68:]),
+			right: le.Uint32(raw[72:]),
+			child: le.Uint32(raw[76:]),
+			start: le.Uint32(raw[116:]),
+			size:  size,
+		})
+	}
+	if len(f.entries) == 0 || f.entries[0].typ != cfbTypeRoot {
+		return nil, fmt.Errorf("%w: missing root entry", errCorruptCFB)
+	}
+
+	// small streams live in the mini stream, addressed through the mini FAT
+	if miniFAT, err := f.chain(le.Uint32(data[0x3C:])); err == nil {
+		for i := 0; i+4 <= len(miniFAT); i += 4 {
+			f.miniFAT = append(f.miniFAT, le.Uint32(miniFAT[i:]))
+		}
+	}
+	root := f.entries[0]
+	if root.start != cfbEndOfChain {
+		if f.miniStream, err = f.chain(root.start); err != nil {
+			return nil, fmt.Errorf("%w: bad mini stream: %v", errCorruptCFB, err)
+		}
+	}
+	return f, nil
+}
+
+func (f *cfbFile) sector(n uint32) ([]byte, error) {
+	off := (int64(n) + 1) * int64(f.sectorSize)
+	if off >= int64(len(f.data)) {
+		return nil, fmt.Errorf("sector %d out of range", n)
+	}
+	if off+int64(f.sectorSize) > int64(len(f.data)) {
+		// the last sector of a file is sometimes truncated
+		return f.data[off:], nil
+	}
+	return f.data[off : off+int64(f.sectorSize)], nil
+}
+
+// chain concatenates the sectors of a FAT chain. A chain can't visit a
+// sector twice or be longer than the file.
+func (f *cfbFile) chain(start uint32) ([]byte, error) {
+	var out []byte
+	visited := map[uint32]bool{}
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect; n = f.fat[n] {
+		if int(n) >= len(f.fat) || visited[n] || len(visited) >= f.sectors {
+			return nil, fmt.Errorf("broken sector chain at %d", n)
+		}
+		visited[n] = true
+		sector, err := f.sector(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sector...)
+	}
+	return out, nil
+}
+
+func (f *cfbFile) miniChain(start uint32) ([]byte, error) {
+	var out []byte
+	visited := map[uint32]bool{}
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect; n = f.miniFAT[n] {
+		off := int(n) * f.miniSize
+		if int(n) >= len(f.miniFAT) || visited[n] || off+f.miniSize > len(f.miniStream) {
+			return nil, fmt.Errorf("broken mini sector chain at %d", n)
+		}
+		visited[n] = true
+		out = append(out, f.miniStream[off:off+f.miniSize]...)
+	}
+	return out, nil
+}
+
+// find returns the entry with the given name among the children of the
+// storage entry parent.
+func (f *cfbFile) find(parent uint32, name string) (uint32, bool) {
+	for _, id := range f.children(parent) {
+		if strings.EqualFold(f.entries[id].name, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// children lists the entries directly inside the storage entry parent.
+func (f *cfbFile) children(parent uint32) []uint32 {
+	if int(parent) >= len(f.entries) {
+		return nil
+	}
+	var ids []uint32
+	stack := []uint32{f.entries[parent].child}
+	for steps := 0; len(stack) > 0 && steps <= len(f.entries); steps++ {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if int(id) >= len(f.entries) {
+			continue
+		}
+		e := f.entries[id]
+		ids = append(ids, id)
+		stack = append(stack, e.left, e.right)
+	}
+	return ids
+}
+
+// stream reads a top-level stream by name.
+func (f *cfbFile) stream(name string) ([]byte, error) {
+	id, ok := f.find(0, name)
+	if !ok || f.entries[id].typ != cfbTypeStream {
+		return nil, fmt.Errorf("stream %q not found", name)
+	}
+	return f.readEntry(f.entries[id])
+}
+
+func (f *cfbFile) readEntry(e cfbEntry) ([]byte, error) {
+	var data []byte
+	var err error
+	if e.size < f.miniCutoff {
+		data, err = f.miniChain(e.start)
+	} else {
+		data, err = f.chain(e.start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) < e.size {
+		return nil, fmt.Errorf("stream %q truncated", e.name)
+	}
+	return data[:e.size], nil
+}
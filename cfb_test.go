@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures link a DIFAT or FAT chain back to itself. Before chains were
+// bounded by the file's sector count, difat-loop.bin grew the FAT until the
+// worker ran out of memory.
+func TestOpenCFBRejectsChainLoops(t *testing.T) {
+	for _, name := range []string{"difat-loop.bin", "difat-loop-empty.bin", "fat-loop.bin"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "cfb", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := openCFB(data); !errors.Is(err, errCorruptCFB) {
+				t.Errorf("openCFB() error = %v, want %v", err, errCorruptCFB)
+			}
+			// sniffing opens the container too
+			if got := sniffMime(data); got == mimeDoc || got == mimeMsg {
+				t.Errorf("sniffMime() = %q for a corrupt compound file", got)
+			}
+		})
+	}
+}
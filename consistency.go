@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/session"
+)
+
+const (
+	consistencyModeTemperature = "temperature"
+	consistencyModeShuffle     = "shuffle"
+)
+
+// ConsistencyConfig enables multi-sample self-consistency scoring. With
+// Samples <= 1 each resume is evaluated once, as before. Samples is the
+// default for sessions that don't set their own count.
+type ConsistencyConfig struct {
+	Samples int
+	// Mode is "temperature" (sample a warmer model) or "shuffle" (reorder
+	// resume sections between samples).
+	Mode        string
+	Temperature float32
+	// MaxStdDev is the score spread above which a result is flagged for
+	// human review.
+	MaxStdDev float64
+}
+
+// Consistency summarises how much the samples agreed.
+type Consistency struct {
+	Samples  []int   `json:"samples"`
+	Median   int     `json:"median"`
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"std_dev"`
+	// Confidence is 1 when all samples agree and falls towards 0 as they
+	// spread out.
+	Confidence  float64 `json:"confidence"`
+	NeedsReview bool    `json:"needs_review"`
+	Failed      int     `json:"failed_samples,omitempty"`
+}
+
+func loadConsistencyConfig(samples, mode, temperature, maxStdDev string) (ConsistencyConfig, error) {
+	cfg := ConsistencyConfig{
+		Samples:     1,
+		Mode:        consistencyModeTemperature,
+		Temperature: 0.7,
+		MaxStdDev:   10,
+	}
+	if samples != "" {
+		n, err := strconv.Atoi(samples)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid sample count %q", samples)
+		}
+		cfg.Samples = n
+	}
+	if mode != "" {
+		if mode != consistencyModeTemperature && mode != consistencyModeShuffle {
+			return cfg, fmt.Errorf("invalid mode %q, expected temperature or shuffle", mode)
+		}
+		cfg.Mode = mode
+	}
+	if temperature != "" {
+		t, err := strconv.ParseFloat(temperature, 32)
+		if err != nil || t <= 0 {
+			return cfg, fmt.Errorf("invalid temperature %q, must be > 0", temperature)
+		}
+		cfg.Temperature = float32(t)
+	}
+	if maxStdDev != "" {
+		d, err := strconv.ParseFloat(maxStdDev, 64)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid max std dev %q", maxStdDev)
+		}
+		cfg.MaxStdDev = d
+	}
+	return cfg, nil
+}
+
+// forSession applies the session's own sample count, if it sets one.
+func (cfg ConsistencyConfig) forSession(s Session) ConsistencyConfig {
+	if s.ConsistencySamples > 0 {
+		cfg.Samples = s.ConsistencySamples
+	}
+	return cfg
+}
+
+var blankLinePattern = regexp.MustCompile(`\n\s*\n`)
+
+// shuffleSections reorders the blank-line separated blocks of a resume. The
+// first block (usually name and contact details) stays in place.
+func shuffleSections(resumeText string, seed uint64) string {
+	blocks := blankLinePattern.Split(strings.TrimSpace(resumeText), -1)
+	if len(blocks) < 3 {
+		return resumeText
+	}
+	rest := blocks[1:]
+	rng := rand.New(rand.NewPCG(seed, seed))
+	rng.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return strings.Join(blocks, "\n\n")
+}
+
+// sampleAnalyses evaluates a resume Samples times, each in a fresh agent
+// session so earlier answers can't anchor later ones. The median score is
+// kept along with the sample whose score is closest to it.
+func sampleAnalyses(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, doc resumeDocument) AnalysesResult {
+	cfg := workerConfig.Consistency.forSession(currentSession)
+	r, appName := workerConfig.AgentRunner, workerConfig.AgentName
+	if cfg.Mode == consistencyModeTemperature {
+		r, appName = workerConfig.SamplingRunner, workerConfig.SamplingAgentName
+	}
+
+	var samples []AnalysesResult
+	var lastErr string
+	for i := range cfg.Samples {
+		sample := doc
+		if cfg.Mode == consistencyModeShuffle && i > 0 {
+			sample.Text = shuffleSections(doc.Text, uint64(i))
+		}
+
+		agentSession, err := workerConfig.AgentSessionService.Create(ctx, &session.CreateRequest{
+			AppName:   appName,
+			UserID:    currentSession.UserID.String(),
+			SessionID: fmt.Sprintf("%s-sample-%d", currentSession.ID, i),
+		})
+		if err != nil {
+			lastErr = fmt.Sprintf("failed to create sample session: %v", err)
+			continue
+		}
+		output, err := retry(2, func() (string, error) {
+			return runAgent(ctx, r, agentSession.Session.UserID(), agentSession.Session.ID(), agentMessage(currentSession, sample))
+		})
+		if delErr := workerConfig.AgentSessionService.Delete(ctx, &session.DeleteRequest{
+			AppName:   appName,
+			UserID:    agentSession.Session.UserID(),
+			SessionID: agentSession.Session.ID(),
+		}); delErr != nil {
+			log.Printf("⚠️ failed to delete sample session %s: %v", agentSession.Session.ID(), delErr)
+		}
+		if err != nil {
+			lastErr = fmt.Sprintf("agent stream error: %v", err)
+			continue
+		}
+
+		result := buildResult(output, false, "")
+		if result.IsErrorResult {
+			lastErr = result.Error
+			continue
+		}
+		samples = append(samples, result)
+	}
+
+	if len(samples) == 0 {
+		return buildResult("", true, fmt.Sprintf("all %d samples failed, last error: %s", cfg.Samples, lastErr))
+	}
+	return aggregateSamples(samples, cfg)
+}
+
+func aggregateSamples(samples []AnalysesResult, cfg ConsistencyConfig) AnalysesResult {
+	scores := make([]int, len(samples))
+	for i, s := range samples {
+		scores[i] = s.MatchScore
+	}
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = int(math.Round(float64(sorted[len(sorted)/2-1]+sorted[len(sorted)/2]) / 2))
+	}
+
+	var mean, variance float64
+	for _, s := range scores {
+		mean += float64(s)
+	}
+	mean /= float64(len(scores))
+	for _, s := range scores {
+		variance += (float64(s) - mean) * (float64(s) - mean)
+	}
+	variance /= float64(len(scores))
+	stdDev := math.Sqrt(variance)
+
+	// the representative sample supplies the narrative fields
+	best := 0
+	for i, s := range scores {
+		if abs(s-median) < abs(scores[best]-median) {
+			best = i
+		}
+	}
+	result := samples[best]
+	result.MatchScore = median
+	result.Consistency = &Consistency{
+		Samples:     scores,
+		Median:      median,
+		Variance:    math.Round(variance*100) / 100,
+		StdDev:      math.Round(stdDev*100) / 100,
+		Confidence:  math.Round(math.Max(0, 1-stdDev/50)*100) / 100,
+		NeedsReview: stdDev > cfg.MaxStdDev || len(samples)*2 <= cfg.Samples,
+		Failed:      cfg.Samples - len(samples),
+	}
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
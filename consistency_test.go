@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAggregateSamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		scores     []int
+		samples    int
+		median     int
+		variance   float64
+		stdDev     float64
+		confidence float64
+		review     bool
+	}{
+		{"all agree", []int{70, 70, 70}, 3, 70, 0, 0, 1, false},
+		{"odd count", []int{60, 80, 70}, 3, 70, 66.67, 8.16, 0.84, false},
+		{"even count rounds the middle pair", []int{70, 75, 60, 90}, 4, 73, 117.19, 10.83, 0.78, true},
+		{"wide spread", []int{20, 50, 90}, 3, 50, 822.22, 28.67, 0.43, true},
+		{"confidence floors at zero", []int{0, 100}, 2, 50, 2500, 50, 0, true},
+		// two of four samples failed; the survivors agree but are too few
+		{"half the samples failed", []int{70, 70}, 4, 70, 0, 0, 1, true},
+		{"a minority of samples failed", []int{70, 72, 71}, 5, 71, 0.67, 0.82, 0.98, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]AnalysesResult, len(tt.scores))
+			for i, s := range tt.scores {
+				samples[i] = AnalysesResult{MatchScore: s}
+			}
+			cfg := ConsistencyConfig{Samples: tt.samples, MaxStdDev: 10}
+			result := aggregateSamples(samples, cfg)
+			c := result.Consistency
+			if c == nil {
+				t.Fatal("no consistency summary")
+			}
+			if result.MatchScore != tt.median || c.Median != tt.median {
+				t.Errorf("MatchScore, Median = %d, %d, want %d", result.MatchScore, c.Median, tt.median)
+			}
+			if c.Variance != tt.variance || c.StdDev != tt.stdDev {
+				t.Errorf("Variance, StdDev = %v, %v, want %v, %v", c.Variance, c.StdDev, tt.variance, tt.stdDev)
+			}
+			if c.Confidence != tt.confidence {
+				t.Errorf("Confidence = %v, want %v", c.Confidence, tt.confidence)
+			}
+			if c.NeedsReview != tt.review {
+				t.Errorf("NeedsReview = %v, want %v", c.NeedsReview, tt.review)
+			}
+			if c.Failed != tt.samples-len(tt.scores) {
+				t.Errorf("Failed = %d, want %d", c.Failed, tt.samples-len(tt.scores))
+			}
+			if !slices.Equal(c.Samples, tt.scores) {
+				t.Errorf("Samples = %v, want %v", c.Samples, tt.scores)
+			}
+		})
+	}
+}
+
+func TestAggregateSamplesKeepsClosestSample(t *testing.T) {
+	samples := []AnalysesResult{
+		{MatchScore: 40, Summary: "harsh"},
+		{MatchScore: 68, Summary: "typical"},
+		{MatchScore: 95, Summary: "generous"},
+		{MatchScore: 72, Summary: "also typical"},
+	}
+	// median of 40, 68, 72, 95 is 70; the first sample at distance 2 wins
+	result := aggregateSamples(samples, ConsistencyConfig{Samples: 4, MaxStdDev: 100})
+	if result.Summary != "typical" || result.MatchScore != 70 {
+		t.Errorf("Summary, MatchScore = %q, %d, want %q, 70", result.Summary, result.MatchScore, "typical")
+	}
+}
+
+func TestConsistencyForSession(t *testing.T) {
+	worker := ConsistencyConfig{Samples: 3, Mode: consistencyModeShuffle, MaxStdDev: 10}
+	tests := []struct {
+		sessionSamples int
+		want           int
+	}{
+		{0, 3},
+		{1, 1},
+		{5, 5},
+	}
+	for _, tt := range tests {
+		got := worker.forSession(Session{ConsistencySamples: tt.sessionSamples})
+		if got.Samples != tt.want {
+			t.Errorf("forSession(%d).Samples = %d, want %d", tt.sessionSamples, got.Samples, tt.want)
+		}
+		if got.Mode != worker.Mode || got.MaxStdDev != worker.MaxStdDev {
+			t.Errorf("forSession(%d) changed the worker's mode or threshold: %+v", tt.sessionSamples, got)
+		}
+	}
+}
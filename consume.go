@@ -2,23 +2,34 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	"github.com/muhammadolammi/jobmatchworker/internal/database"
 	"github.com/streadway/amqp"
 	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/genai"
 )
 
+// permanentError marks an error retrying can't fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
 // retry retries a function up to `attempts` times with exponential backoff
 func retry[T any](attempts int, fn func() (T, error)) (T, error) {
 	var zero T
@@ -29,6 +40,9 @@ func retry[T any](attempts int, fn func() (T, error)) (T, error) {
 		if err == nil {
 			return result, nil
 		}
+		if errors.As(err, new(permanentError)) {
+			return zero, err
+		}
 		lastErr = err
 		wait := time.Duration(500*(i+1)) * time.Millisecond
 		time.Sleep(wait)
@@ -36,7 +50,7 @@ func retry[T any](attempts int, fn func() (T, error)) (T, error) {
 	return zero, fmt.Errorf("after %d attempts: %w", attempts, lastErr)
 }
 
-func aggregateResult(results *AnalysesResults, resultStr string, hasError bool, errorMsg string) {
+func buildResult(resultStr string, hasError bool, errorMsg string) AnalysesResult {
 	result := AnalysesResult{}
 	switch {
 	case hasError:
@@ -54,9 +68,378 @@ func aggregateResult(results *AnalysesResults, resultStr string, hasError bool,
 			result.IsErrorResult = true
 			result.Error = "json unmarshal error: " + err.Error()
 		}
+		result.Method = scoringMethodLLM
+	}
+
+	return result
+}
+
+// resumeDocument is extracted resume text plus what the worker derived
+// from it before scoring.
+type resumeDocument struct {
+	// Label identifies the resume in logs.
+	Label      string
+	Text       string
+	Experience *ExperienceSummary
+	// CoverLetter is the application email and any cover letter sent with
+	// the resume.
+	CoverLetter string
+}
+
+// agentMessage builds the agent input for one resume.
+func agentMessage(currentSession Session, doc resumeDocument) string {
+	msg := fmt.Sprintf(
+		"Job Title:\n%s\n\nJob Description:\n%s\n\nResume:\n%s",
+		currentSession.JobTitle,
+		currentSession.JobDescription,
+		doc.Text,
+	)
+	if doc.Experience != nil && len(doc.Experience.Ranges) > 0 {
+		msg += "\n\n" + experienceContext(doc.Experience)
+	}
+	if doc.CoverLetter != "" {
+		msg += "\n\nCover Letter (context only, score the resume):\n" + doc.CoverLetter
+	}
+	return msg
+}
+
+// runAgent sends msg to the agent and returns its final response text.
+func runAgent(ctx context.Context, r *runner.Runner, userID, sessionID, msg string) (string, error) {
+	stream := r.Run(ctx, userID, sessionID, &genai.Content{
+		Role: "user",
+		Parts: []*genai.Part{
+			{Text: msg},
+		},
+	}, agent.RunConfig{})
+
+	var output string
+	for event, err := range stream {
+		if err != nil {
+			return "", err
+		}
+		if event != nil && event.IsFinalResponse() && len(event.Content.Parts) > 0 {
+			output = event.Content.Parts[0].Text
+		}
+	}
+
+	if output == "" {
+		return "", fmt.Errorf("empty agent response")
+	}
+	return output, nil
+}
+
+// analyzeResume downloads a single resume upload and scores it. A ZIP
+// upload yields a result per file inside it.
+func analyzeResume(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, resume database.Resume) (results []AnalysesResult) {
+	// a panic on one resume must not take down the worker and every other
+	// in-flight session
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("💥 Panic analyzing %s: %v\n%s", resume.ObjectKey, r, debug.Stack())
+			results = []AnalysesResult{buildResult("", true, "internal error while analyzing resume")}
+		}
+	}()
+
+	// ✅ Retry downloading file (network failures are transient)
+	blob, err := retry(3, func() (*Blob, error) {
+		return workerConfig.Storage.Download(ctx, resume)
+	})
+	if errors.Is(err, errFileTooLarge) {
+		log.Printf("⚠️ Refusing to download %s: %v", resume.ObjectKey, err)
+		result := buildResult("", true, err.Error())
+		result.ErrorCode = errorCodeTooLarge
+		return []AnalysesResult{result}
+	}
+	if errors.Is(err, errIntegrity) {
+		log.Printf("⚠️ Integrity check failed for %s: %v", resume.ObjectKey, err)
+		result := buildResult("", true, err.Error())
+		result.ErrorCode = errorCodeIntegrity
+		return []AnalysesResult{result}
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to download %s after retries: %v", resume.ObjectKey, err)
+		// return buildResult("", true, fmt.Sprintf("file download error: %v", err))
+		return []AnalysesResult{buildResult("", true, "file download error")}
+	}
+	defer blob.Close()
+	fileBytes := blob.Data
+
+	// ✅ Scan before any parser sees the file
+	threat, err := retry(3, func() (string, error) {
+		return workerConfig.Scanner.Scan(ctx, fileBytes)
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to scan %s after retries: %v", resume.ObjectKey, err)
+		return []AnalysesResult{buildResult("", true, "malware scan error")}
+	}
+	if threat != "" {
+		return []AnalysesResult{quarantineResume(ctx, workerConfig, resume, threat)}
+	}
+
+	// trust the content over the uploader's declared type
+	resolved, err := resolveFileMime(workerConfig, resume.Mime, fileBytes)
+	if err != nil {
+		log.Printf("⚠️ Failed to sniff %s: %v", resume.ObjectKey, err)
+		return []AnalysesResult{buildResult("", true, fmt.Sprintf("file type detection error: %v", err))}
+	}
+	switch resolved.Mime {
+	case mimeZip:
+		return analyzeArchive(ctx, workerConfig, currentSession, agentSession, resume, fileBytes)
+	case mimeEmail, mimeMsg:
+		return analyzeEmail(ctx, workerConfig, currentSession, agentSession, resume, resolved.Mime, fileBytes)
+	}
+	cached := loadResumeText(ctx, workerConfig, resume, blob.SHA256)
+	result, profile := analyzeFile(ctx, workerConfig, currentSession, agentSession, resume.ObjectKey, resolved, fileBytes, "", cached)
+	if cached == nil && result.Extraction != nil {
+		saveResumeText(ctx, workerConfig, resume, blob.SHA256, *result.Extraction)
+	}
+	if profile != nil {
+		saveResumeProfile(ctx, workerConfig, resume, profile)
+	}
+	return []AnalysesResult{result}
+}
+
+// quarantineResume moves an infected upload out of reach and reports it
+// without parsing it.
+func quarantineResume(ctx context.Context, workerConfig *WorkerConfig, resume database.Resume, threat string) AnalysesResult {
+	log.Printf("☣️ Malware detected in %s: %s", resume.ObjectKey, threat)
+	key, err := workerConfig.Storage.Quarantine(ctx, resume)
+	if err != nil {
+		log.Printf("⚠️ Failed to quarantine %s: %v", resume.ObjectKey, err)
+	} else {
+		log.Printf("🔒 Quarantined %s as %s", resume.ObjectKey, key)
+	}
+	result := buildResult("", true, fmt.Sprintf("file rejected: malware detected (%s)", threat))
+	result.ErrorCode = errorCodeInfected
+	return result
+}
+
+// analyzeArchive scores each file in an uploaded ZIP. Files that can't be
+// read or analysed get an error result of their own.
+func analyzeArchive(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, resume database.Resume, data []byte) []AnalysesResult {
+	entries, err := workerConfig.Sandbox.ExpandArchive(data, workerConfig.Archive)
+	if err != nil {
+		log.Printf("⚠️ Failed to expand archive %s: %v", resume.ObjectKey, err)
+		result := buildResult("", true, fmt.Sprintf("archive error: %v", err))
+		result.Archive = resume.OriginalFilename
+		return []AnalysesResult{result}
+	}
+	if len(entries) == 0 {
+		result := buildResult("", true, "archive error: no files in archive")
+		result.Archive = resume.OriginalFilename
+		return []AnalysesResult{result}
+	}
+	log.Printf("📦 Expanded %s into %d files", resume.ObjectKey, len(entries))
+
+	var results []AnalysesResult
+	for _, entry := range entries {
+		var result AnalysesResult
+		if entry.Err != nil {
+			log.Printf("⚠️ Skipping %s in %s: %v", entry.Path, resume.ObjectKey, entry.Err)
+			result = buildResult("", true, fmt.Sprintf("archive error: %v", entry.Err))
+		} else {
+			// profiles are stored per resume row, which archive entries
+			// don't have
+			result, _ = analyzeEntry(ctx, workerConfig, currentSession, agentSession, resume.ObjectKey+"/"+entry.Path, entry.Mime, entry.Data, "")
+		}
+		result.Archive = resume.OriginalFilename
+		result.Filename = entry.Path
+		results = append(results, result)
+	}
+	return results
+}
+
+// analyzeEmail scores the resumes attached to an application email, with the
+// email body as cover letter context. An email without attachments is
+// scored on its body.
+func analyzeEmail(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, resume database.Resume, mime string, data []byte) []AnalysesResult {
+	email, err := workerConfig.Sandbox.ParseEmail(mime, data)
+	if err != nil {
+		log.Printf("⚠️ Failed to parse email %s: %v", resume.ObjectKey, err)
+		return []AnalysesResult{buildResult("", true, fmt.Sprintf("email error: %v", err))}
+	}
+	summary := &EmailSummary{From: email.From, Subject: email.Subject, Date: email.Date}
+
+	attachments, letters := resumeAttachments(email, workerConfig.Extractors)
+	var letterTexts []string
+	for _, letter := range letters {
+		extraction, err := workerConfig.Extractors.Extract(resume.ObjectKey+"/"+letter.Filename, letter.Mime, letter.Data)
+		if err != nil {
+			log.Printf("⚠️ Failed to read cover letter %s in %s: %v", letter.Filename, resume.ObjectKey, err)
+			continue
+		}
+		letterTexts = append(letterTexts, strings.TrimSpace(extraction.Text))
+	}
+	summary.CoverLetter = coverLetter(email, letterTexts)
+
+	if len(attachments) == 0 {
+		if strings.TrimSpace(email.Body) == "" {
+			result := buildResult("", true, "email error: no resume attachment found")
+			result.Email = summary
+			return []AnalysesResult{result}
+		}
+		// a resume pasted into the email body
+		log.Printf("📧 No resume attached to %s, scoring the email body", resume.ObjectKey)
+		summary.CoverLetter = ""
+		body := resolvedMime{Declared: mimeText, Mime: mimeText, Detected: mimeText}
+		result, profile := analyzeFile(ctx, workerConfig, currentSession, agentSession, resume.ObjectKey, body, []byte(email.Body), "", nil)
+		if profile != nil {
+			saveResumeProfile(ctx, workerConfig, resume, profile)
+		}
+		result.Email = summary
+		return []AnalysesResult{result}
 	}
 
-	results.Results = append(results.Results, result)
+	log.Printf("📧 Found %d resume attachments in %s", len(attachments), resume.ObjectKey)
+	var results []AnalysesResult
+	for _, a := range attachments {
+		result, profile := analyzeEntry(ctx, workerConfig, currentSession, agentSession, resume.ObjectKey+"/"+a.Filename, a.Mime, a.Data, summary.CoverLetter)
+		// profiles are stored per resume row, so only an email carrying a
+		// single resume gets one
+		if profile != nil && len(attachments) == 1 {
+			saveResumeProfile(ctx, workerConfig, resume, profile)
+		}
+		result.Filename = a.Filename
+		result.Email = summary
+		results = append(results, result)
+	}
+	return results
+}
+
+// resolvedMime is a file's declared MIME type and the type resolved from its
+// content.
+type resolvedMime struct {
+	Declared string
+	Mime     string
+	Detected string
+}
+
+// resolveFileMime sniffs data in the sandbox.
+func resolveFileMime(workerConfig *WorkerConfig, declared string, data []byte) (resolvedMime, error) {
+	mime, detected, err := workerConfig.Sandbox.ResolveMime(declared, data)
+	return resolvedMime{Declared: declared, Mime: mime, Detected: detected}, err
+}
+
+// analyzeEntry sniffs and analyses a file taken out of an archive or email,
+// whose type so far is only guessed from its name.
+func analyzeEntry(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, label, declaredMime string, data []byte, coverLetter string) (AnalysesResult, *ResumeProfile) {
+	resolved, err := resolveFileMime(workerConfig, declaredMime, data)
+	if err != nil {
+		log.Printf("⚠️ Failed to sniff %s: %v", label, err)
+		return buildResult("", true, fmt.Sprintf("file type detection error: %v", err)), nil
+	}
+	return analyzeFile(ctx, workerConfig, currentSession, agentSession, label, resolved, data, coverLetter, nil)
+}
+
+// analyzeFile extracts and scores one file, returning the structured profile
+// when the text was good enough to analyse. label identifies the file in
+// logs; coverLetter is passed to the agent as context. cached is text stored
+// by an earlier run, used instead of extracting again.
+func analyzeFile(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, label string, resolved resolvedMime, fileBytes []byte, coverLetter string, cached *Extraction) (AnalysesResult, *ResumeProfile) {
+	mismatch := resolved.Declared != "" && resolved.Mime != normalizeMime(resolved.Declared)
+	if mismatch {
+		log.Printf("⚠️ MIME mismatch for %s: declared %q, detected %q", label, resolved.Declared, resolved.Detected)
+	}
+
+	// Extract text from file
+	var result AnalysesResult
+	var profile *ResumeProfile
+	var extraction Extraction
+	var err error
+	if cached != nil {
+		extraction = *cached
+	} else {
+		extraction, err = workerConfig.Extractors.Extract(label, resolved.Mime, fileBytes)
+	}
+	if err != nil {
+		log.Printf("⚠️ Text extraction failed for %s: %v", label, err)
+		result = buildResult("", true, fmt.Sprintf("text extraction error: %v", err))
+	} else {
+		quality := assessTextQuality(extraction.Text)
+		extraction.Quality = &quality
+		if quality.Unreadable {
+			// garbage text would only buy a meaningless analysis
+			log.Printf("⚠️ Unreadable text extracted from %s: %s", label, strings.Join(quality.Reasons, "; "))
+			result = buildResult("", true, "unreadable document: "+strings.Join(quality.Reasons, "; "))
+			result.ErrorCode = errorCodeUnreadable
+		} else {
+			profile = extraction.Profile
+			if profile == nil {
+				profile = buildProfile(extraction.Text, time.Now())
+			}
+			result = scoreResume(ctx, workerConfig, currentSession, agentSession, resumeDocument{
+				Label:       label,
+				Text:        extraction.Text,
+				CoverLetter: coverLetter,
+			})
+		}
+	}
+	if extraction.Extractor != "" {
+		result.Extraction = &extraction
+	}
+
+	if mismatch {
+		result.DeclaredMime = resolved.Declared
+		result.DetectedMime = resolved.Detected
+	}
+	return result, profile
+}
+
+// scoreResume runs knockout checks and scoring on extracted resume text.
+func scoreResume(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, doc resumeDocument) AnalysesResult {
+	doc.Experience = computeExperience(doc.Text, jdSkills(currentSession.JobTitle, currentSession.JobDescription), time.Now(), workerConfig.EmploymentGapMonths)
+	result := evaluateResume(ctx, workerConfig, currentSession, agentSession, doc)
+	if !result.IsErrorResult {
+		result.Experience = doc.Experience
+	}
+	return result
+}
+
+func evaluateResume(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, agentSession session.Session, doc resumeDocument) AnalysesResult {
+	label, resumeText := doc.Label, doc.Text
+	// hard requirements first, so knocked out candidates skip the full analysis
+	knockedOut, err := applyKnockoutRules(ctx, workerConfig, currentSession, resumeText)
+	if err != nil {
+		log.Printf("⚠️ Knockout check failed for %s: %v", label, err)
+		return buildResult("", true, fmt.Sprintf("knockout check error: %v", err))
+	}
+	if knockedOut != nil {
+		return *knockedOut
+	}
+
+	if workerConfig.ScoringMode == scoringMethodLexical {
+		return lexicalScore(currentSession.JobTitle, currentSession.JobDescription, resumeText)
+	}
+
+	var result AnalysesResult
+	if workerConfig.Consistency.forSession(currentSession).Samples > 1 {
+		result = sampleAnalyses(ctx, workerConfig, currentSession, doc)
+	} else {
+		// ✅ Retry the AI agent stream separately (in case of transient agent failures)
+		finalOutput, streamErr := retry(2, func() (string, error) {
+			return runAgent(ctx, workerConfig.AgentRunner, agentSession.UserID(), agentSession.ID(), agentMessage(currentSession, doc))
+		})
+
+		if streamErr != nil {
+			log.Printf("⚠️ Agent failed for %s after retries: %v", label, streamErr)
+			// log.Println("agent output: ", finalOutput)
+			result = buildResult("", true, fmt.Sprintf("agent stream error: %v", streamErr))
+		} else {
+			// log.Println("agent output: ", finalOutput)
+
+			result = buildResult(finalOutput, false, "")
+		}
+	}
+	if !result.IsErrorResult {
+		hybridScore(&result, workerConfig.ScoreWeights, currentSession.JobTitle, currentSession.JobDescription, resumeText)
+	}
+
+	// degraded mode: rank on keywords rather than return nothing
+	if result.IsErrorResult && workerConfig.LexicalFallback {
+		log.Printf("⚠️ Falling back to lexical scoring for %s: %s", label, result.Error)
+		result = lexicalFallback(currentSession, resumeText, result)
+	}
+	return result
 }
 
 // callAgent runs the agent pipeline for all resumes in a given session.
@@ -85,73 +468,11 @@ func callAgent(currentSession Session, workerConfig *WorkerConfig) error {
 	}
 	// process each resume
 	for _, resume := range resumes {
-
-		awsClient := s3.NewFromConfig(*workerConfig.AwsConfig, func(o *s3.Options) {
-			o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", workerConfig.R2.AccountID))
-		})
-
-		// ✅ Retry downloading file (network failures are transient)
-		fileBytes, err := retry(3, func() ([]byte, error) {
-			return DownloadFromR2(ctx, awsClient, workerConfig.R2.Bucket, resume.ObjectKey)
-		})
-		if err != nil {
-			log.Printf("⚠️ Failed to download %s after retries: %v", resume.ObjectKey, err)
-			// aggregateResult(results, "", true, fmt.Sprintf("file download error: %v", err))
-			aggregateResult(results, "", true, "file download error")
-
-			continue
-		}
-
-		// Extract text from file
-		resumeText, err := ExtractResumeText(resume.Mime, fileBytes)
-		if err != nil {
-			log.Printf("⚠️ Text extraction failed for %s: %v", resume.ObjectKey, err)
-			aggregateResult(results, "", true, fmt.Sprintf("text extraction error: %v", err))
-			continue
-		}
-
-		// Build AI input
-		msg := fmt.Sprintf(
-			"Job Title:\n%s\n\nJob Description:\n%s\n\nResume:\n%s",
-			currentSession.JobTitle,
-			currentSession.JobDescription,
-			resumeText,
-		)
-
-		// ✅ Retry the AI agent stream separately (in case of transient agent failures)
-		finalOutput, streamErr := retry(2,
-			func() (string, error) {
-				stream := workerConfig.AgentRunner.Run(ctx, agentSession.Session.UserID(), agentSession.Session.ID(), &genai.Content{
-					Role: "user",
-					Parts: []*genai.Part{
-						{Text: msg},
-					},
-				}, agent.RunConfig{})
-
-				var output string
-				for event, err := range stream {
-					if err != nil {
-						return "", err
-					}
-					if event != nil && event.IsFinalResponse() && len(event.Content.Parts) > 0 {
-						output = event.Content.Parts[0].Text
-					}
-				}
-
-				if output == "" {
-					return "", fmt.Errorf("empty agent response")
-				}
-				return output, nil
-			})
-
-		if streamErr != nil {
-			log.Printf("⚠️ Agent failed for %s after retries: %v", resume.ObjectKey, streamErr)
-			// log.Println("agent output: ", finalOutput)
-			aggregateResult(results, "", true, fmt.Sprintf("agent stream error: %v", streamErr))
-		} else {
-			// log.Println("agent output: ", finalOutput)
-
-			aggregateResult(results, finalOutput, false, "")
+		for _, result := range analyzeResume(ctx, workerConfig, currentSession, agentSession.Session, resume) {
+			if result.Filename == "" {
+				result.Filename = resume.OriginalFilename
+			}
+			results.Results = append(results.Results, result)
 		}
 	}
 	log.Println("session id: " + agentSession.Session.ID() + " analyzed")
@@ -180,9 +501,63 @@ func callAgent(currentSession Session, workerConfig *WorkerConfig) error {
 	if err != nil {
 		return fmt.Errorf("failed to save agent result after retries: %w", err)
 	}
+	publishReport(ctx, workerConfig, currentSession, results.Results)
+	if _, _, err := publishExports(ctx, workerConfig, currentSession.ID, results.Results); err != nil {
+		log.Printf("⚠️ Failed to export session %s: %v", currentSession.ID, err)
+	}
 
 	return nil
 }
+
+// errSessionNotRunnable is returned for sessions that were deleted after
+// being queued, or whose status doesn't allow a run: cancelled, already
+// processing or finished.
+var errSessionNotRunnable = errors.New("session is not runnable")
+
+// runnableStatuses are the session statuses a queued message may start
+// analysing; anything else is skipped.
+var runnableStatuses = map[string]bool{"pending": true, "queued": true}
+
+// loadSession reads the session to analyse from the database.
+func loadSession(ctx context.Context, workerConfig *WorkerConfig, id uuid.UUID) (Session, error) {
+	row, err := retry(3, func() (database.GetSessionRow, error) {
+		row, err := workerConfig.DB.GetSession(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return row, permanentError{fmt.Errorf("%w: session was deleted", errSessionNotRunnable)}
+		}
+		return row, err
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if !runnableStatuses[strings.ToLower(row.Status)] {
+		return Session{}, fmt.Errorf("%w: session is %q", errSessionNotRunnable, row.Status)
+	}
+
+	session := Session{
+		ID:             row.ID,
+		CreatedAt:      row.CreatedAt,
+		Name:           row.Name,
+		UserID:         row.UserID,
+		Status:         row.Status,
+		JobTitle:       row.JobTitle,
+		JobDescription: row.JobDescription,
+	}
+	if err := json.Unmarshal(row.KnockoutRules, &session.KnockoutRules); err != nil {
+		return Session{}, permanentError{fmt.Errorf("invalid knockout rules: %w", err)}
+	}
+	for i, rule := range session.KnockoutRules {
+		if err := rule.validate(); err != nil {
+			return Session{}, permanentError{fmt.Errorf("invalid knockout rule %d: %w", i+1, err)}
+		}
+	}
+	if row.ConsistencySamples < 0 {
+		return Session{}, permanentError{fmt.Errorf("invalid consistency sample count %d", row.ConsistencySamples)}
+	}
+	session.ConsistencySamples = int(row.ConsistencySamples)
+	return session, nil
+}
+
 func worker(id int, workerConfig *WorkerConfig, wg *sync.WaitGroup) {
 	defer wg.Done()
 	//    to consume message on the queue
@@ -224,25 +599,35 @@ func worker(id int, workerConfig *WorkerConfig, wg *sync.WaitGroup) {
 
 	for msg := range msgs {
 		// Unmarshal the body
-		session := Session{}
-		// log.Println(string(msg.Body))
-		err = json.Unmarshal(msg.Body, &session)
-		// log.Println(session)
+		message := SessionMessage{}
+		err = json.Unmarshal(msg.Body, &message)
+		sessionID := message.sessionID()
+		if err != nil || sessionID == uuid.Nil {
+			log.Printf("invalid session message, skipping. body: %q err: %v", msg.Body, err)
+			continue
+		}
 
+		// the message only names the session; its job description and
+		// rules come from the database
+		session, err := loadSession(context.Background(), workerConfig, sessionID)
+		if errors.Is(err, errSessionNotRunnable) {
+			log.Printf("skipping session_id: %s: %v", sessionID, err)
+			continue
+		}
 		if err != nil {
-			log.Printf("error unmarshalling message body. err: %v", err)
+			log.Printf("error loading session_id: %s. err: %v", sessionID, err)
 			// update session status as failed
 			workerConfig.DB.UpdateSessionStatus(context.Background(), database.UpdateSessionStatusParams{
 				Status: "failed",
-				ID:     session.ID,
+				ID:     sessionID,
 			})
 			update := map[string]any{
-				"session_id": session.ID,
+				"session_id": sessionID,
 				"status":     "failed",
 				"message":    "analysis failed",
 				"timestamp":  time.Now(),
 			}
-			err := publishSessionUpdate(workerConfig.RabbitConn, session.ID.String(), update)
+			err := publishSessionUpdate(workerConfig.RabbitConn, sessionID.String(), update)
 			if err != nil {
 				log.Println("failed to publish update:", err)
 			}
@@ -257,7 +642,7 @@ func worker(id int, workerConfig *WorkerConfig, wg *sync.WaitGroup) {
 			"message":    "analysis started",
 			"timestamp":  time.Now(),
 		}
-		err := publishSessionUpdate(workerConfig.RabbitConn, session.ID.String(), update)
+		err = publishSessionUpdate(workerConfig.RabbitConn, session.ID.String(), update)
 		if err != nil {
 			log.Println("failed to publish update:", err)
 		}
@@ -314,7 +699,8 @@ func worker(id int, workerConfig *WorkerConfig, wg *sync.WaitGroup) {
 
 func (workerConfig *WorkerConfig) StartConsumerWorkerPool(numWorkers int) {
 	var wg sync.WaitGroup
-	wg.Add(numWorkers)
+	wg.Add(numWorkers + 1)
+	go exportWorker(workerConfig, &wg)
 
 	for i := range numWorkers {
 		log.Println("worker id ", i+1, "started")
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	wordIdent = 0xA5EC
+	// nFib of Word 97; older versions use a different file layout
+	minWordFib = 101
+	// offset of fcClx/lcbClx in the FIB
+	fibClxOffset = 0x01A2
+)
+
+var errEncryptedDocument = errors.New("document is password protected")
+
+// extractDocText returns the text of a Word 97-2003 .doc file. The text is
+// read through the piece table, so fast-saved documents come out in
+// document order; field codes are dropped in favour of their results and
+// table cells become "cell | cell" lines.
+func extractDocText(data []byte) (string, error) {
+	cfb, err := openCFB(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read doc: %w", err)
+	}
+	wordDoc, err := cfb.stream("WordDocument")
+	if err != nil {
+		return "", fmt.Errorf("failed to read doc: %w", err)
+	}
+	if len(wordDoc) < fibClxOffset+8 {
+		return "", fmt.Errorf("failed to read doc: FIB too short")
+	}
+
+	le := binary.LittleEndian
+	if le.Uint16(wordDoc[0:]) != wordIdent {
+		return "", fmt.Errorf("failed to read doc: not a Word document")
+	}
+	if le.Uint16(wordDoc[2:]) < minWordFib {
+		return "", fmt.Errorf("failed to read doc: Word 95 and older files are not supported")
+	}
+	flags := le.Uint16(wordDoc[0x0A:])
+	if flags&0x0100 != 0 {
+		return "", errEncryptedDocument
+	}
+	tableName := "0Table"
+	if flags&0x0200 != 0 {
+		tableName = "1Table"
+	}
+	table, err := cfb.stream(tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read doc: %w", err)
+	}
+
+	fcClx := le.Uint32(wordDoc[fibClxOffset:])
+	lcbClx := le.Uint32(wordDoc[fibClxOffset+4:])
+	if lcbClx == 0 || uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return "", fmt.Errorf("failed to read doc: missing piece table")
+	}
+	pieces, err := parseClx(table[fcClx : fcClx+lcbClx])
+	if err != nil {
+		return "", fmt.Errorf("failed to read doc: %w", err)
+	}
+
+	var text []rune
+	for _, p := range pieces {
+		chars, err := p.read(wordDoc)
+		if err != nil {
+			return "", fmt.Errorf("failed to read doc: %w", err)
+		}
+		text = append(text, chars...)
+	}
+
+	// the main story comes first, followed by footnotes, headers, comments,
+	// endnotes and text boxes; keep them all, main story first
+	return blankLinesPattern.ReplaceAllString(strings.TrimSpace(renderDocChars(text)), "\n\n"), nil
+}
+
+// docPiece is one run of the piece table: a range of characters stored
+// either as cp1252 bytes or as UTF-16.
+type docPiece struct {
+	cpStart, cpEnd uint32
+	fc             uint32
+	compressed     bool
+}
+
+func (p docPiece) read(wordDoc []byte) ([]rune, error) {
+	n := int(p.cpEnd - p.cpStart)
+	if p.compressed {
+		start := int(p.fc / 2)
+		if start+n > len(wordDoc) {
+			return nil, fmt.Errorf("piece out of range")
+		}
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(wordDoc[start : start+n])
+		if err != nil {
+			return nil, err
+		}
+		return []rune(string(decoded)), nil
+	}
+	start := int(p.fc)
+	if start+2*n > len(wordDoc) {
+		return nil, fmt.Errorf("piece out of range")
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(wordDoc[start+2*i:])
+	}
+	return utf16.Decode(units), nil
+}
+
+// parseClx skips the property modifiers (Prc) at the start of the Clx and
+// decodes the piece table (Pcdt) that follows.
+func parseClx(clx []byte) ([]docPiece, error) {
+	le := binary.LittleEndian
+	i := 0
+	for i < len(clx) && clx[i] == 0x01 {
+		if i+3 > len(clx) {
+			return nil, fmt.Errorf("truncated Clx")
+		}
+		i += 3 + int(le.Uint16(clx[i+1:]))
+	}
+	if i+5 > len(clx) || clx[i] != 0x02 {
+		return nil, fmt.Errorf("missing piece table")
+	}
+	lcb := int(le.Uint32(clx[i+1:]))
+	plc := clx[i+5:]
+	if lcb > len(plc) || lcb < 4 || (lcb-4)%12 != 0 {
+		return nil, fmt.Errorf("malformed piece table")
+	}
+	plc = plc[:lcb]
+
+	// n+1 character positions followed by n 8-byte piece descriptors
+	n := (lcb - 4) / 12
+	pcds := plc[4*(n+1):]
+	pieces := make([]docPiece, 0, n)
+	for j := range n {
+		start, end := le.Uint32(plc[4*j:]), le.Uint32(plc[4*(j+1):])
+		if end < start {
+			return nil, fmt.Errorf("malformed piece table")
+		}
+		fc := le.Uint32(pcds[8*j+2:])
+		pieces = append(pieces, docPiece{
+			cpStart:    start,
+			cpEnd:      end,
+			fc:         fc & 0x3FFFFFFF,
+			compressed: fc&0x40000000 != 0,
+		})
+	}
+	return pieces, nil
+}
+
+// renderDocChars maps Word's special characters to plain text.
+func renderDocChars(chars []rune) string {
+	var b bytes.Buffer
+	// one entry per open field: whether we're still in its instructions
+	var fields []bool
+	var code strings.Builder
+	var links []string
+	inCode := func() bool { return len(fields) > 0 && fields[len(fields)-1] }
+	paragraphStart := 0
+	endParagraph := func() {
+		current := b.Bytes()[paragraphStart:]
+		for _, link := range links {
+			display := strings.TrimPrefix(link, "mailto:")
+			if !bytes.Contains(current, []byte(display)) {
+				b.WriteString(" (" + display + ")")
+			}
+		}
+		links = nil
+		b.WriteByte('\n')
+		paragraphStart = b.Len()
+	}
+	var lastCell bool
+
+	for _, c := range chars {
+		cell := false
+		switch c {
+		case 0x13: // field begin
+			fields = append(fields, true)
+			code.Reset()
+		case 0x14: // field separator: instructions end, result follows
+			if inCode() {
+				fields[len(fields)-1] = false
+				if m := fieldHyperlinkArgs.FindStringSubmatch(code.String()); m != nil {
+					links = append(links, m[1])
+				}
+			}
+		case 0x15: // field end
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+		default:
+			if inCode() {
+				code.WriteRune(c)
+				continue
+			}
+			switch c {
+			case '\r':
+				endParagraph()
+			case 0x07:
+				// cell mark; a second one right after ends the table row
+				if lastCell {
+					if bytes.HasSuffix(b.Bytes(), []byte(" | ")) {
+						b.Truncate(b.Len() - 3)
+					}
+					endParagraph()
+				} else {
+					b.WriteString(" | ")
+				}
+				cell = true
+			case 0x0B, 0x0C:
+				b.WriteByte('\n')
+			case 0x1E:
+				b.WriteByte('-')
+			case '\t':
+				b.WriteByte('\t')
+			case 0xA0:
+				b.WriteByte(' ')
+			default:
+				// pictures, footnote references and other anchors
+				if c >= 0x20 {
+					b.WriteRune(c)
+				}
+			}
+		}
+		lastCell = cell
+	}
+	endParagraph()
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	headerPartPattern  = regexp.MustCompile(`^word/header\d*\.xml$`)
+	footerPartPattern  = regexp.MustCompile(`^word/footer\d*\.xml$`)
+	fieldHyperlinkArgs = regexp.MustCompile(`HYPERLINK\s+"([^"]+)"`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// extractDocxText returns the text of a .docx file: headers first (where
+// contact details often live), then the body, footers, footnotes and
+// endnotes. Paragraphs become lines, table rows become "cell | cell" lines,
+// text boxes are included and hyperlink targets follow their link text.
+func extractDocxText(reader io.Reader) (string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse docx: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files["word/document.xml"] == nil {
+		return "", fmt.Errorf("failed to parse docx: missing word/document.xml")
+	}
+
+	var headers, footers []string
+	for name := range files {
+		switch {
+		case headerPartPattern.MatchString(name):
+			headers = append(headers, name)
+		case footerPartPattern.MatchString(name):
+			footers = append(footers, name)
+		}
+	}
+	sort.Strings(headers)
+	sort.Strings(footers)
+
+	parts := append([]string(nil), headers...)
+	parts = append(parts, "word/document.xml")
+	parts = append(parts, footers...)
+	parts = append(parts, "word/footnotes.xml", "word/endnotes.xml")
+
+	var sections []string
+	seen := map[string]bool{}
+	for _, name := range parts {
+		f := files[name]
+		if f == nil {
+			continue
+		}
+		rels, err := readDocxRels(files[path.Join(path.Dir(name), "_rels", path.Base(name)+".rels")])
+		if err != nil {
+			return "", fmt.Errorf("failed to read relationships for %s: %w", name, err)
+		}
+		text, err := readDocxPart(f, rels)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		text = strings.TrimSpace(text)
+		// first/even/default headers usually repeat the same text
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		sections = append(sections, text)
+	}
+
+	return blankLinesPattern.ReplaceAllString(strings.Join(sections, "\n\n"), "\n\n"), nil
+}
+
+// readDocxRels maps relationship ids to their targets.
+func readDocxRels(f *zip.File) (map[string]string, error) {
+	rels := map[string]string{}
+	if f == nil {
+		return rels, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var doc struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, r := range doc.Relationships {
+		rels[r.ID] = r.Target
+	}
+	return rels, nil
+}
+
+func isWordML(name xml.Name) bool {
+	return strings.HasSuffix(name.Space, "wordprocessingml/2006/main") || strings.HasSuffix(name.Space, "wordprocessingml/main")
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxParagraph collects the text of one w:p.
+type docxParagraph struct {
+	text strings.Builder
+	list bool
+	// links from HYPERLINK fields, appended at the end of the paragraph
+	fieldLinks []string
+}
+
+// docxCell collects the paragraphs of one table cell.
+type docxCell struct {
+	paragraphs []string
+}
+
+type docxTable struct {
+	rows []string
+	row  []string
+}
+
+// docxWalker walks a WordprocessingML part and keeps just enough structure
+// to lay the text out as lines.
+type docxWalker struct {
+	rels       map[string]string
+	lines      []string
+	paragraphs []*docxParagraph
+	cells      []*docxCell
+	tables     []*docxTable
+	// hyperlink targets of the w:hyperlink elements currently open
+	links []string
+}
+
+func (w *docxWalker) paragraph() *docxParagraph {
+	if len(w.paragraphs) == 0 {
+		return nil
+	}
+	return w.paragraphs[len(w.paragraphs)-1]
+}
+
+func (w *docxWalker) write(s string) {
+	if p := w.paragraph(); p != nil {
+		p.text.WriteString(s)
+	}
+}
+
+// emit adds a finished line to the innermost cell, or to the output.
+func (w *docxWalker) emit(line string) {
+	if len(w.cells) > 0 {
+		cell := w.cells[len(w.cells)-1]
+		cell.paragraphs = append(cell.paragraphs, line)
+		return
+	}
+	w.lines = append(w.lines, line)
+}
+
+func readDocxPart(f *zip.File, rels map[string]string) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	w := &docxWalker{rels: rels}
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			// markup-compatibility fallbacks repeat the Choice content (e.g.
+			// VML copies of text boxes)
+			if el.Name.Local == "Fallback" {
+				if err := dec.Skip(); err != nil {
+					return "", err
+				}
+				continue
+			}
+			if !isWordML(el.Name) {
+				continue
+			}
+			switch el.Name.Local {
+			case "p":
+				w.paragraphs = append(w.paragraphs, &docxParagraph{})
+			case "numPr":
+				if p := w.paragraph(); p != nil {
+					p.list = true
+				}
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &el); err != nil {
+					return "", err
+				}
+				w.write(text)
+			case "delText":
+				// tracked deletions aren't part of the document
+				if err := dec.Skip(); err != nil {
+					return "", err
+				}
+			case "instrText":
+				var instr string
+				if err := dec.DecodeElement(&instr, &el); err != nil {
+					return "", err
+				}
+				if m := fieldHyperlinkArgs.FindStringSubmatch(instr); m != nil {
+					if p := w.paragraph(); p != nil {
+						p.fieldLinks = append(p.fieldLinks, m[1])
+					}
+				}
+			case "tab", "ptab":
+				w.write("\t")
+			case "br", "cr":
+				w.write("\n")
+			case "noBreakHyphen", "softHyphen":
+				w.write("-")
+			case "hyperlink":
+				target := ""
+				if id := xmlAttr(el, "id"); id != "" {
+					target = w.rels[id]
+				}
+				w.links = append(w.links, target)
+			case "tbl":
+				w.tables = append(w.tables, &docxTable{})
+			case "tc":
+				w.cells = append(w.cells, &docxCell{})
+			}
+
+		case xml.EndElement:
+			if !isWordML(el.Name) {
+				continue
+			}
+			switch el.Name.Local {
+			case "p":
+				p := w.paragraph()
+				if p == nil {
+					continue
+				}
+				w.paragraphs = w.paragraphs[:len(w.paragraphs)-1]
+				line := strings.TrimRight(p.text.String(), " \t")
+				for _, link := range p.fieldLinks {
+					if !strings.Contains(line, link) {
+						line += " (" + link + ")"
+					}
+				}
+				if p.list && strings.TrimSpace(line) != "" {
+					line = "- " + strings.TrimSpace(line)
+				}
+				w.emit(line)
+			case "hyperlink":
+				if len(w.links) == 0 {
+					continue
+				}
+				target := w.links[len(w.links)-1]
+				w.links = w.links[:len(w.links)-1]
+				display := strings.TrimPrefix(target, "mailto:")
+				if p := w.paragraph(); p != nil && display != "" && !strings.Contains(p.text.String(), display) {
+					w.write(" (" + display + ")")
+				}
+			case "tc":
+				if len(w.cells) == 0 {
+					continue
+				}
+				cell := w.cells[len(w.cells)-1]
+				w.cells = w.cells[:len(w.cells)-1]
+				if len(w.tables) > 0 {
+					t := w.tables[len(w.tables)-1]
+					t.row = append(t.row, strings.Join(nonEmpty(cell.paragraphs), " "))
+				}
+			case "tr":
+				if len(w.tables) == 0 {
+					continue
+				}
+				t := w.tables[len(w.tables)-1]
+				if row := strings.Join(nonEmpty(t.row), " | "); row != "" {
+					t.rows = append(t.rows, row)
+				}
+				t.row = nil
+			case "tbl":
+				if len(w.tables) == 0 {
+					continue
+				}
+				t := w.tables[len(w.tables)-1]
+				w.tables = w.tables[:len(w.tables)-1]
+				for _, row := range t.rows {
+					w.emit(row)
+				}
+			}
+		}
+	}
+
+	return strings.Join(w.lines, "\n"), nil
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// checkGolden compares got with the golden file, or rewrites it with
+// -update.
+func checkGolden(t *testing.T, golden, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("text differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+// TestExtractDocxText checks each testdata/docx/<name>.docx against the
+// expected text in <name>.txt.
+func TestExtractDocxText(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "docx", "*.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no .docx fixtures found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := extractDocxText(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("extractDocxText: %v", err)
+			}
+			checkGolden(t, strings.TrimSuffix(path, ".docx")+".txt", text)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// emails nest through forwards and multipart/alternative; anything
+	// deeper than this is not a real application
+	maxEmailDepth = 8
+	maxEmailParts = 200
+	// the cover letter is context, not the document being scored
+	maxCoverLetterLength = 4000
+)
+
+// emailMessage is an application email: its body is the candidate's cover
+// letter, its attachments the resume and anything else they sent.
+type emailMessage struct {
+	From        string
+	Subject     string
+	Date        string
+	Body        string
+	Attachments []emailAttachment
+}
+
+// EmailSummary is stored with the results of resumes received by email.
+type EmailSummary struct {
+	From        string `json:"from,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	Date        string `json:"date,omitempty"`
+	CoverLetter string `json:"cover_letter,omitempty"`
+}
+
+type emailAttachment struct {
+	Filename string
+	Mime     string
+	Data     []byte
+	// Inline parts are embedded in the HTML body: logos and signature
+	// images
+	Inline bool
+}
+
+var (
+	resumeFilePattern      = regexp.MustCompile(`(?i)(\bcv\b|_cv|cv_|-cv|cv-|resume|résumé|curriculum|lebenslauf|\bvitae\b)`)
+	coverLetterFilePattern = regexp.MustCompile(`(?i)(cover|motivation|lettre|anschreiben|letter)`)
+	emailHeaderPattern     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*:[ \t]`)
+)
+
+// looksLikeEmail recognises an RFC 822 message by its header block.
+func looksLikeEmail(trimmed []byte) bool {
+	if !emailHeaderPattern.Match(trimmed) {
+		return false
+	}
+	lower := strings.ToLower(string(trimmed))
+	hits := 0
+	for _, h := range []string{"from:", "to:", "subject:", "date:", "received:", "mime-version:", "message-id:", "return-path:"} {
+		if strings.HasPrefix(lower, h) || strings.Contains(lower, "\n"+h) {
+			hits++
+		}
+	}
+	return hits >= 3
+}
+
+// parseEmail reads an RFC 822 message and its MIME tree.
+func parseEmail(data []byte) (*emailMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+	email := &emailMessage{
+		From:    decodeHeader(msg.Header.Get("From")),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+		Date:    msg.Header.Get("Date"),
+	}
+	w := &emailWalker{email: email}
+	if err := w.walk(mimeHeader(msg.Header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+	email.Body = strings.TrimSpace(w.body())
+	return email, nil
+}
+
+// mimeHeader is the subset of a part's headers the walker needs.
+type mimeHeader interface {
+	Get(key string) string
+}
+
+type emailWalker struct {
+	email *emailMessage
+	parts int
+	plain []string
+	html  []string
+}
+
+// body prefers the plain text alternatives over the HTML ones.
+func (w *emailWalker) body() string {
+	if len(w.plain) > 0 {
+		return strings.Join(w.plain, "\n\n")
+	}
+	return strings.Join(w.html, "\n\n")
+}
+
+func (w *emailWalker) walk(header mimeHeader, body io.Reader, depth int) error {
+	w.parts++
+	if depth > maxEmailDepth || w.parts > maxEmailParts {
+		return fmt.Errorf("email nested too deep or has too many parts")
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = mimeText
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				// a truncated final part still leaves the earlier ones
+				return nil
+			}
+			if err := w.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode email part: %w", err)
+	}
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case mediaType == "message/rfc822":
+		// a forwarded application: its attachments are ours, its body is
+		// more context
+		forwarded, err := mail.ReadMessage(bytes.NewReader(content))
+		if err != nil {
+			return nil
+		}
+		return w.walk(mimeHeader(forwarded.Header), forwarded.Body, depth+1)
+	case disposition != "attachment" && filename == "" && mediaType == mimeText:
+		w.plain = append(w.plain, decodeCharset(content, params["charset"]))
+	case disposition != "attachment" && filename == "" && mediaType == mimeHTML:
+		text, err := extractHTMLText(content)
+		if err == nil {
+			w.html = append(w.html, text)
+		}
+	default:
+		if filename == "" {
+			filename = "attachment" + extensionFor(mediaType)
+		}
+		w.email.Attachments = append(w.email.Attachments, emailAttachment{
+			Filename: path.Base(strings.ReplaceAll(filename, `\`, "/")),
+			Mime:     mediaType,
+			Data:     content,
+			Inline:   disposition == "inline" || header.Get("Content-ID") != "" && disposition != "attachment",
+		})
+	}
+	return nil
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// line breaks and stray whitespace are common in base64 bodies
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Cleaner drops everything that isn't base64 alphabet.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '+' || b == '/' || b == '=' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+func decodeHeader(s string) string {
+	decoded, err := headerDecoder.DecodeHeader(s)
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+func decodeCharset(content []byte, label string) string {
+	if label == "" {
+		return string(content)
+	}
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(content))
+	if err != nil {
+		return string(content)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return string(content)
+	}
+	return string(decoded)
+}
+
+func extensionFor(mediaType string) string {
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// Outlook .msg files are compound files holding MAPI properties as
+// "__substg1.0_<property id><type>" streams, with one storage per
+// attachment.
+const (
+	msgPropertiesStream = "__properties_version1.0"
+	msgAttachPrefix     = "__attach_version1.0_"
+
+	mapiString  = "001F"
+	mapiString8 = "001E"
+	mapiBinary  = "0102"
+
+	mapiSubject        = "0037"
+	mapiSenderName     = "0C1A"
+	mapiSenderEmail    = "0C1F"
+	mapiBody           = "1000"
+	mapiBodyHTML       = "1013"
+	mapiTransportHdrs  = "007D"
+	mapiAttachData     = "3701"
+	mapiAttachFilename = "3704"
+	mapiAttachLongName = "3707"
+	mapiAttachMime     = "370E"
+)
+
+// isOutlookMsg reports whether a compound file is an Outlook message.
+func isOutlookMsg(cfb *cfbFile) bool {
+	_, props := cfb.find(0, msgPropertiesStream)
+	_, subject := cfb.find(0, "__substg1.0_"+mapiSubject+mapiString)
+	_, body := cfb.find(0, "__substg1.0_"+mapiBody+mapiString)
+	_, nameid := cfb.find(0, "__nameid_version1.0")
+	return props && (subject || body || nameid)
+}
+
+// parseOutlookMsg reads an Outlook .msg file.
+func parseOutlookMsg(data []byte) (*emailMessage, error) {
+	cfb, err := openCFB(data)
+	if err != nil {
+		return nil, err
+	}
+	if !isOutlookMsg(cfb) {
+		return nil, fmt.Errorf("not an Outlook message")
+	}
+	email := &emailMessage{
+		Subject: msgString(cfb, 0, mapiSubject),
+		From:    strings.TrimSpace(msgString(cfb, 0, mapiSenderName) + " <" + msgString(cfb, 0, mapiSenderEmail) + ">"),
+		Body:    strings.TrimSpace(msgString(cfb, 0, mapiBody)),
+	}
+	if email.From == "<>" {
+		email.From = ""
+	}
+	if headers := msgString(cfb, 0, mapiTransportHdrs); headers != "" {
+		if msg, err := mail.ReadMessage(strings.NewReader(headers + "\r\n\r\n")); err == nil {
+			email.Date = msg.Header.Get("Date")
+		}
+	}
+	if email.Body == "" {
+		if html, ok := msgProperty(cfb, 0, mapiBodyHTML+mapiBinary); ok {
+			if text, err := extractHTMLText(html); err == nil {
+				email.Body = strings.TrimSpace(text)
+			}
+		}
+	}
+
+	for _, id := range cfb.children(0) {
+		entry := cfb.entries[id]
+		if entry.typ != cfbTypeStorage || !strings.HasPrefix(entry.name, msgAttachPrefix) {
+			continue
+		}
+		// embedded messages (PT_OBJECT) have no binary data stream and
+		// are skipped
+		content, ok := msgProperty(cfb, id, mapiAttachData+mapiBinary)
+		if !ok {
+			continue
+		}
+		filename := msgString(cfb, id, mapiAttachLongName)
+		if filename == "" {
+			filename = msgString(cfb, id, mapiAttachFilename)
+		}
+		email.Attachments = append(email.Attachments, emailAttachment{
+			Filename: path.Base(strings.ReplaceAll(filename, `\`, "/")),
+			Mime:     msgString(cfb, id, mapiAttachMime),
+			Data:     content,
+		})
+	}
+	return email, nil
+}
+
+func msgProperty(cfb *cfbFile, storage uint32, tag string) ([]byte, bool) {
+	id, ok := cfb.find(storage, "__substg1.0_"+tag)
+	if !ok || cfb.entries[id].typ != cfbTypeStream {
+		return nil, false
+	}
+	data, err := cfb.readEntry(cfb.entries[id])
+	return data, err == nil
+}
+
+// msgString reads a string property, stored as UTF-16 or, in older files,
+// in the ANSI code page.
+func msgString(cfb *cfbFile, storage uint32, prop string) string {
+	if data, ok := msgProperty(cfb, storage, prop+mapiString); ok {
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			units = append(units, binary.LittleEndian.Uint16(data[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	}
+	if data, ok := msgProperty(cfb, storage, prop+mapiString8); ok {
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(bytes.TrimRight(data, "\x00"))
+		if err != nil {
+			return string(data)
+		}
+		return string(decoded)
+	}
+	return ""
+}
+
+// parseEmailMessage parses an email (mimeEmail) or Outlook message
+// (mimeMsg), resolving each attachment's MIME type from its content.
+func parseEmailMessage(mime string, data []byte) (*emailMessage, error) {
+	var email *emailMessage
+	var err error
+	if mime == mimeMsg {
+		email, err = parseOutlookMsg(data)
+	} else {
+		email, err = parseEmail(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range email.Attachments {
+		email.Attachments[i].Mime, _ = resolveMime(a.Mime, a.Data)
+	}
+	return email, nil
+}
+
+// resumeAttachments splits an email's attachments into the resumes to score
+// and the cover letters to read alongside them. Attachments named like a
+// resume win; failing that every attachment the registry can extract counts.
+func resumeAttachments(email *emailMessage, extractors *ExtractorRegistry) (resumes, coverLetters []emailAttachment) {
+	var candidates []emailAttachment
+	for _, a := range email.Attachments {
+		if a.Inline || len(a.Data) == 0 {
+			continue
+		}
+		// Mime was resolved when the email was parsed
+		if _, ok := extractors.For(a.Mime, a.Data); !ok {
+			continue
+		}
+		if coverLetterFilePattern.MatchString(a.Filename) && !resumeFilePattern.MatchString(a.Filename) {
+			coverLetters = append(coverLetters, a)
+			continue
+		}
+		candidates = append(candidates, a)
+	}
+	for _, a := range candidates {
+		if resumeFilePattern.MatchString(a.Filename) {
+			resumes = append(resumes, a)
+		}
+	}
+	if len(resumes) == 0 {
+		resumes = candidates
+	}
+	return resumes, coverLetters
+}
+
+// coverLetter joins the email body and any cover letter attachments,
+// capped so the resume stays the bulk of the agent's input.
+func coverLetter(email *emailMessage, letters []string) string {
+	parts := []string{}
+	if email.Subject != "" {
+		parts = append(parts, "Subject: "+email.Subject)
+	}
+	if email.Body != "" {
+		parts = append(parts, email.Body)
+	}
+	parts = append(parts, letters...)
+	text := strings.TrimSpace(strings.Join(parts, "\n\n"))
+	if runes := []rune(text); len(runes) > maxCoverLetterLength {
+		text = string(runes[:maxCoverLetterLength]) + "…"
+	}
+	return text
+}
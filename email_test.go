@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const testEmail = "From: =?UTF-8?B?SmFuZSBEw7Vl?= <jane@example.com>\r\n" +
+	"To: jobs@example.com\r\n" +
+	"Subject: Application: Backend Engineer\r\n" +
+	"Date: Mon, 2 Jun 2025 09:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=alt\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Hello, please find my CV attached. I have five years of Go experien=\r\n" +
+	"ce.\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>Hello, <b>HTML</b> version</p>\r\n" +
+	"--alt--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/octet-stream; name=\"Jane_Doe_CV.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"Jane_Doe_CV.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQKJcOkw7zDtsOfCg==\r\n" +
+	"--outer\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"From: recruiter@example.com\r\n" +
+	"Subject: Fwd: referral\r\n" +
+	"Content-Type: multipart/mixed; boundary=fwd\r\n" +
+	"\r\n" +
+	"--fwd\r\n" +
+	"Content-Type: application/rtf\r\n" +
+	"Content-Disposition: attachment; filename=\"C:\\Users\\jane\\cover letter.rtf\"\r\n" +
+	"\r\n" +
+	"{\\rtf1\\ansi Dear hiring manager}\r\n" +
+	"--fwd--\r\n" +
+	"--outer--\r\n"
+
+func TestParseEmailMessageEML(t *testing.T) {
+	email, err := parseEmailMessage(mimeEmail, []byte(testEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.From != "Jane Dõe <jane@example.com>" {
+		t.Errorf("From = %q", email.From)
+	}
+	if email.Subject != "Application: Backend Engineer" {
+		t.Errorf("Subject = %q", email.Subject)
+	}
+	// the plain alternative wins over the HTML one
+	if want := "Hello, please find my CV attached. I have five years of Go experience."; email.Body != want {
+		t.Errorf("Body = %q, want %q", email.Body, want)
+	}
+
+	want := []struct {
+		filename string
+		mime     string
+		inline   bool
+	}{
+		{"attachment.png", mimePNG, true},
+		{"Jane_Doe_CV.pdf", mimePDF, false},
+		// the forwarded message's attachment, with the Windows path dropped
+		{"cover letter.rtf", mimeRTF, false},
+	}
+	if len(email.Attachments) != len(want) {
+		t.Fatalf("got %d attachments, want %d: %+v", len(email.Attachments), len(want), email.Attachments)
+	}
+	for i, w := range want {
+		a := email.Attachments[i]
+		if a.Filename != w.filename || a.Mime != w.mime || a.Inline != w.inline {
+			t.Errorf("attachment %d = %q %q inline=%v, want %q %q inline=%v", i, a.Filename, a.Mime, a.Inline, w.filename, w.mime, w.inline)
+		}
+	}
+	if !bytes.HasPrefix(email.Attachments[1].Data, pdfMagic) {
+		t.Errorf("resume attachment not base64 decoded: %q", email.Attachments[1].Data)
+	}
+}
+
+func TestParseEmailMessageRejectsGarbage(t *testing.T) {
+	if _, err := parseEmailMessage(mimeEmail, []byte("not an email")); err == nil {
+		t.Error("parseEmailMessage(mimeEmail) accepted a non-email")
+	}
+	if _, err := parseEmailMessage(mimeMsg, []byte(testEmail)); err == nil {
+		t.Error("parseEmailMessage(mimeMsg) accepted an RFC 822 message")
+	}
+}
+
+// cfbNode is a storage (with children) or stream (with data) for cfbWith.
+type cfbNode struct {
+	name     string
+	data     []byte
+	children []cfbNode
+}
+
+// cfbWith builds a version 3 compound file holding nodes under the root.
+// The mini stream cutoff is zero so every stream lives in regular sectors.
+func cfbWith(t *testing.T, nodes []cfbNode) []byte {
+	t.Helper()
+	const sectorSize = 512
+	le := binary.LittleEndian
+
+	type flat struct {
+		name  string
+		typ   byte
+		data  []byte
+		child uint32
+		right uint32
+	}
+	entries := []flat{{name: "Root Entry", typ: cfbTypeRoot, child: cfbFreeSect, right: cfbFreeSect}}
+	var add func(nodes []cfbNode) uint32
+	add = func(nodes []cfbNode) uint32 {
+		// siblings are chained through their right pointers
+		first := uint32(cfbFreeSect)
+		prev := -1
+		for _, n := range nodes {
+			id := len(entries)
+			e := flat{name: n.name, typ: cfbTypeStream, data: n.data, child: cfbFreeSect, right: cfbFreeSect}
+			if n.children != nil {
+				e.typ = cfbTypeStorage
+			}
+			entries = append(entries, e)
+			if n.children != nil {
+				entries[id].child = add(n.children)
+			}
+			if prev < 0 {
+				first = uint32(id)
+			} else {
+				entries[prev].right = uint32(id)
+			}
+			prev = id
+		}
+		return first
+	}
+	entries[0].child = add(nodes)
+
+	sectorsFor := func(n int) int { return (n + sectorSize - 1) / sectorSize }
+	dirSectors := sectorsFor(len(entries) * cfbDirEntry)
+	streamSectors := 0
+	for _, e := range entries {
+		streamSectors += sectorsFor(len(e.data))
+	}
+	// sector 0 holds the FAT, then the directory, then the streams
+	if 1+dirSectors+streamSectors > sectorSize/4 {
+		t.Fatal("cfbWith: file too large for a single FAT sector")
+	}
+	fat := make([]uint32, sectorSize/4)
+	for i := range fat {
+		fat[i] = cfbFreeSect
+	}
+	fat[0] = 0xFFFFFFFD // FAT sector
+	chain := func(start, n int) {
+		for i := 0; i < n; i++ {
+			fat[start+i] = uint32(start + i + 1)
+		}
+		fat[start+n-1] = cfbEndOfChain
+	}
+	chain(1, dirSectors)
+
+	body := make([]byte, sectorSize*(1+dirSectors))
+	next := 1 + dirSectors
+	var streams []byte
+	for i, e := range entries {
+		raw := body[sectorSize+i*cfbDirEntry : sectorSize+(i+1)*cfbDirEntry]
+		units := utf16.Encode([]rune(e.name))
+		for j, u := range units {
+			le.PutUint16(raw[2*j:], u)
+		}
+		le.PutUint16(raw[64:], uint16(2*len(units)+2))
+		raw[66] = e.typ
+		le.PutUint32(raw[68:], cfbFreeSect)
+		le.PutUint32(raw[72:], e.right)
+		le.PutUint32(raw[76:], e.child)
+		le.PutUint32(raw[116:], cfbEndOfChain)
+		if n := sectorsFor(len(e.data)); n > 0 {
+			le.PutUint32(raw[116:], uint32(next))
+			le.PutUint64(raw[120:], uint64(len(e.data)))
+			chain(next, n)
+			padded := make([]byte, n*sectorSize)
+			copy(padded, e.data)
+			streams = append(streams, padded...)
+			next += n
+		}
+	}
+	for i, v := range fat {
+		le.PutUint32(body[4*i:], v)
+	}
+
+	header := make([]byte, cfbHeaderSize)
+	copy(header, oleMagic)
+	le.PutUint16(header[0x18:], 0x3E)
+	le.PutUint16(header[0x1A:], 3)
+	le.PutUint16(header[0x1C:], 0xFFFE)
+	le.PutUint16(header[0x1E:], 9)
+	le.PutUint16(header[0x20:], 6)
+	le.PutUint32(header[0x2C:], 1)
+	le.PutUint32(header[0x30:], 1)
+	le.PutUint32(header[0x38:], 0)
+	le.PutUint32(header[0x3C:], cfbEndOfChain)
+	le.PutUint32(header[0x44:], cfbEndOfChain)
+	le.PutUint32(header[0x4C:], 0)
+	for i := 1; i < 109; i++ {
+		le.PutUint32(header[0x4C+4*i:], cfbFreeSect)
+	}
+	return slices.Concat(header, body, streams)
+}
+
+// msgString16 encodes a PT_UNICODE property value.
+func msgString16(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestParseEmailMessageMsg(t *testing.T) {
+	pdf, _ := base64.StdEncoding.DecodeString("JVBERi0xLjQKJcOkw7zDtsOfCg==")
+	msg := cfbWith(t, []cfbNode{
+		{name: msgPropertiesStream, data: make([]byte, 32)},
+		{name: "__substg1.0_" + mapiSubject + mapiString, data: msgString16("Application for Data Engineer")},
+		{name: "__substg1.0_" + mapiSenderName + mapiString, data: msgString16("Jane Doe")},
+		{name: "__substg1.0_" + mapiSenderEmail + mapiString, data: msgString16("jane@example.com")},
+		{name: "__substg1.0_" + mapiBody + mapiString8, data: []byte("Dear team,\r\nmy r\xe9sum\xe9 is attached.\x00")},
+		{name: "__substg1.0_" + mapiTransportHdrs + mapiString, data: msgString16("Date: Tue, 3 Jun 2025 10:00:00 +0000\r\nFrom: jane@example.com")},
+		{name: msgAttachPrefix + "#00000000", children: []cfbNode{
+			{name: "__substg1.0_" + mapiAttachData + mapiBinary, data: pdf},
+			{name: "__substg1.0_" + mapiAttachFilename + mapiString, data: msgString16("RESUME~1.PDF")},
+			{name: "__substg1.0_" + mapiAttachLongName + mapiString, data: msgString16("resume.pdf")},
+		}},
+		// an embedded message has no data stream and is skipped
+		{name: msgAttachPrefix + "#00000001", children: []cfbNode{
+			{name: "__substg1.0_" + mapiAttachLongName + mapiString, data: msgString16("forwarded.msg")},
+		}},
+	})
+	if got := sniffMime(msg); got != mimeMsg {
+		t.Fatalf("sniffMime() = %q, want %q", got, mimeMsg)
+	}
+
+	email, err := parseEmailMessage(mimeMsg, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.Subject != "Application for Data Engineer" {
+		t.Errorf("Subject = %q", email.Subject)
+	}
+	if email.From != "Jane Doe <jane@example.com>" {
+		t.Errorf("From = %q", email.From)
+	}
+	if email.Date != "Tue, 3 Jun 2025 10:00:00 +0000" {
+		t.Errorf("Date = %q", email.Date)
+	}
+	// the ANSI body is decoded from Windows-1252
+	if want := "Dear team,\r\nmy résumé is attached."; email.Body != want {
+		t.Errorf("Body = %q, want %q", email.Body, want)
+	}
+	if len(email.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1: %+v", len(email.Attachments), email.Attachments)
+	}
+	if a := email.Attachments[0]; a.Filename != "resume.pdf" || a.Mime != mimePDF || !bytes.Equal(a.Data, pdf) {
+		t.Errorf("attachment = %q %q %q", a.Filename, a.Mime, a.Data)
+	}
+}
+
+func TestResumeAttachments(t *testing.T) {
+	extractors := NewExtractorRegistry(builtinExtractors()...)
+	pdf := []byte("%PDF-1.4\n")
+	rtf := []byte(`{\rtf1 text}`)
+	attachment := func(name, mime string, data []byte) emailAttachment {
+		return emailAttachment{Filename: name, Mime: mime, Data: data}
+	}
+	tests := []struct {
+		name         string
+		attachments  []emailAttachment
+		resumes      []string
+		coverLetters []string
+	}{
+		{
+			name: "named resume wins",
+			attachments: []emailAttachment{
+				attachment("portfolio.pdf", mimePDF, pdf),
+				attachment("Jane_CV.pdf", mimePDF, pdf),
+				attachment("cover_letter.rtf", mimeRTF, rtf),
+			},
+			resumes:      []string{"Jane_CV.pdf"},
+			coverLetters: []string{"cover_letter.rtf"},
+		},
+		{
+			name: "every extractable attachment without a named resume",
+			attachments: []emailAttachment{
+				attachment("jane.pdf", mimePDF, pdf),
+				attachment("doe.rtf", mimeRTF, rtf),
+			},
+			resumes: []string{"jane.pdf", "doe.rtf"},
+		},
+		{
+			name: "resume name beats cover letter name",
+			attachments: []emailAttachment{
+				attachment("resume and cover letter.pdf", mimePDF, pdf),
+			},
+			resumes: []string{"resume and cover letter.pdf"},
+		},
+		{
+			name: "inline, empty and unsupported attachments skipped",
+			attachments: []emailAttachment{
+				{Filename: "logo.png", Mime: mimePNG, Data: pngMagic, Inline: true},
+				attachment("cv.pdf", mimePDF, nil),
+				attachment("cv.exe", mimeOctet, []byte("MZ")),
+			},
+		},
+	}
+	names := func(attachments []emailAttachment) []string {
+		var out []string
+		for _, a := range attachments {
+			out = append(out, a.Filename)
+		}
+		return out
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumes, coverLetters := resumeAttachments(&emailMessage{Attachments: tt.attachments}, extractors)
+			if got := strings.Join(names(resumes), ","); got != strings.Join(tt.resumes, ",") {
+				t.Errorf("resumes = %q, want %q", got, strings.Join(tt.resumes, ","))
+			}
+			if got := strings.Join(names(coverLetters), ","); got != strings.Join(tt.coverLetters, ",") {
+				t.Errorf("cover letters = %q, want %q", got, strings.Join(tt.coverLetters, ","))
+			}
+		})
+	}
+}
+
+func TestCoverLetter(t *testing.T) {
+	email := &emailMessage{Subject: "Application", Body: "Hello"}
+	if got, want := coverLetter(email, []string{"Dear team"}), "Subject: Application\n\nHello\n\nDear team"; got != want {
+		t.Errorf("coverLetter() = %q, want %q", got, want)
+	}
+	if got := coverLetter(&emailMessage{}, nil); got != "" {
+		t.Errorf("coverLetter() of an empty email = %q", got)
+	}
+
+	long := coverLetter(&emailMessage{Body: strings.Repeat("é", maxCoverLetterLength+10)}, nil)
+	if runes := []rune(long); len(runes) != maxCoverLetterLength+1 || runes[len(runes)-1] != '…' {
+		t.Errorf("long cover letter is %d runes ending %q, want %d ending with an ellipsis", len(runes), string(runes[len(runes)-1]), maxCoverLetterLength+1)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errNotEuropass = errors.New("not a Europass CV")
+
+// europassCV is the part of the Europass XML CV (SkillsPassport, schema
+// v3) that maps onto ResumeProfile. Descriptions are HTML fragments.
+type europassCV struct {
+	XMLName     xml.Name `xml:"SkillsPassport"`
+	Locale      string   `xml:"locale,attr"`
+	LearnerInfo struct {
+		Identification struct {
+			FirstName   string `xml:"PersonName>FirstName"`
+			Surname     string `xml:"PersonName>Surname"`
+			ContactInfo struct {
+				Address struct {
+					Line         string `xml:"AddressLine"`
+					Municipality string `xml:"Municipality"`
+					CountryCode  string `xml:"Country>Code"`
+					Country      string `xml:"Country>Label"`
+				} `xml:"Address>Contact"`
+				Email      string   `xml:"Email>Contact"`
+				Telephones []string `xml:"TelephoneList>Telephone>Contact"`
+				Websites   []string `xml:"WebsiteList>Website>Contact"`
+			} `xml:"ContactInfo"`
+		} `xml:"Identification"`
+		Headline       string `xml:"Headline>Description>Label"`
+		WorkExperience []struct {
+			Period     europassPeriod `xml:"Period"`
+			Position   string         `xml:"Position>Label"`
+			Activities string         `xml:"Activities"`
+			Employer   string         `xml:"Employer>Name"`
+		} `xml:"WorkExperienceList>WorkExperience"`
+		Education []struct {
+			Period       europassPeriod `xml:"Period"`
+			Title        string         `xml:"Title"`
+			Activities   string         `xml:"Activities"`
+			Organisation string         `xml:"Organisation>Name"`
+			Field        string         `xml:"Field>Label"`
+		} `xml:"EducationList>Education"`
+		Skills struct {
+			MotherTongues    []string `xml:"Linguistic>MotherTongueList>MotherTongue>Description>Label"`
+			ForeignLanguages []struct {
+				Language    string `xml:"Description>Label"`
+				Proficiency struct {
+					Listening         string
+					Reading           string
+					SpokenInteraction string
+					SpokenProduction  string
+					Writing           string
+				} `xml:"ProficiencyLevel"`
+			} `xml:"Linguistic>ForeignLanguageList>ForeignLanguage"`
+			Communication  string   `xml:"Communication>Description"`
+			Organisational string   `xml:"Organisational>Description"`
+			JobRelated     string   `xml:"JobRelated>Description"`
+			Computer       string   `xml:"Computer>Description"`
+			Driving        []string `xml:"Driving>Description>Licence"`
+			Other          string   `xml:"Other>Description"`
+		} `xml:"Skills"`
+		Achievements []struct {
+			Code        string `xml:"Title>Code"`
+			Label       string `xml:"Title>Label"`
+			Description string `xml:"Description"`
+		} `xml:"AchievementList>Achievement"`
+	} `xml:"LearnerInfo"`
+}
+
+type europassPeriod struct {
+	From    europassDate `xml:"From"`
+	To      europassDate `xml:"To"`
+	Current bool         `xml:"Current"`
+}
+
+// europassDate has year="2020" month="--01" day="---15" attributes.
+type europassDate struct {
+	Year  string `xml:"year,attr"`
+	Month string `xml:"month,attr"`
+}
+
+func (d europassDate) iso() string {
+	month := strings.TrimLeft(d.Month, "-")
+	if d.Year == "" || month == "" {
+		return d.Year
+	}
+	return d.Year + "-" + month
+}
+
+// extractEuropassCV maps a Europass XML CV onto the profile, and renders it
+// as text for the agent.
+func extractEuropassCV(data []byte) (Extraction, error) {
+	var cv europassCV
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	decoder.Strict = false
+	if err := decoder.Decode(&cv); err != nil {
+		var unexpected xml.UnmarshalError
+		if errors.As(err, &unexpected) {
+			return Extraction{}, errNotEuropass
+		}
+		return Extraction{}, fmt.Errorf("invalid XML: %w", err)
+	}
+
+	var warnings []string
+	info := cv.LearnerInfo
+	id := info.Identification
+	profile := &ResumeProfile{Basics: ProfileBasics{
+		Name:  strings.TrimSpace(id.FirstName + " " + id.Surname),
+		Label: strings.TrimSpace(info.Headline),
+		Email: strings.TrimSpace(id.ContactInfo.Email),
+	}}
+	if len(id.ContactInfo.Telephones) > 0 {
+		profile.Basics.Phone = strings.TrimSpace(id.ContactInfo.Telephones[0])
+	}
+	for _, site := range id.ContactInfo.Websites {
+		links, website := findLinks(site)
+		profile.Basics.Profiles = append(profile.Basics.Profiles, links...)
+		if profile.Basics.URL == "" {
+			profile.Basics.URL = website
+		}
+	}
+	if addr := id.ContactInfo.Address; addr.Municipality != "" || addr.CountryCode != "" {
+		profile.Basics.Location = &ProfileLocation{Address: addr.Line, City: addr.Municipality, CountryCode: addr.CountryCode}
+	}
+
+	for _, w := range info.WorkExperience {
+		work := ProfileWork{
+			Name:      strings.TrimSpace(w.Employer),
+			Position:  strings.TrimSpace(w.Position),
+			StartDate: w.Period.From.iso(),
+			Summary:   europassHTML(w.Activities, &warnings),
+		}
+		if !w.Period.Current {
+			work.EndDate = w.Period.To.iso()
+		}
+		profile.Work = append(profile.Work, work)
+	}
+	for _, e := range info.Education {
+		profile.Education = append(profile.Education, ProfileEducation{
+			Institution: strings.TrimSpace(e.Organisation),
+			StudyType:   strings.TrimSpace(e.Title),
+			Area:        strings.TrimSpace(e.Field),
+			StartDate:   e.Period.From.iso(),
+			EndDate:     e.Period.To.iso(),
+		})
+	}
+
+	skills := info.Skills
+	for _, lang := range skills.MotherTongues {
+		profile.Languages = append(profile.Languages, ProfileLanguage{Language: strings.TrimSpace(lang), Fluency: "Native"})
+	}
+	for _, lang := range skills.ForeignLanguages {
+		p := lang.Proficiency
+		levels := nonEmpty([]string{p.Listening, p.Reading, p.SpokenInteraction, p.SpokenProduction, p.Writing})
+		fluency := ""
+		if len(levels) > 0 {
+			// the CEFR levels sort alphabetically, so the lowest is the
+			// honest overall level
+			fluency = levels[0]
+			for _, l := range levels[1:] {
+				fluency = min(fluency, l)
+			}
+		}
+		profile.Languages = append(profile.Languages, ProfileLanguage{Language: strings.TrimSpace(lang.Language), Fluency: fluency})
+	}
+	for _, s := range []struct{ name, html string }{
+		{"Communication", skills.Communication},
+		{"Organisational", skills.Organisational},
+		{"Job-related", skills.JobRelated},
+		{"Digital", skills.Computer},
+		{"Other", skills.Other},
+	} {
+		text := europassHTML(s.html, &warnings)
+		if text == "" {
+			continue
+		}
+		var keywords []string
+		for _, line := range strings.Split(text, "\n") {
+			keywords = append(keywords, nonEmpty(listSeparators.Split(bulletPattern.ReplaceAllString(line, ""), -1))...)
+		}
+		profile.Skills = append(profile.Skills, ProfileSkill{Name: s.name, Keywords: keywords})
+	}
+	if len(skills.Driving) > 0 {
+		profile.Skills = append(profile.Skills, ProfileSkill{Name: "Driving licence", Keywords: skills.Driving})
+	}
+
+	for _, a := range info.Achievements {
+		description := europassHTML(a.Description, &warnings)
+		switch strings.ToLower(a.Code) {
+		case "certifications", "courses":
+			for _, line := range nonEmpty(strings.Split(description, "\n")) {
+				profile.Certificates = append(profile.Certificates, ProfileCertificate{Name: bulletPattern.ReplaceAllString(line, "")})
+			}
+		case "projects":
+			profile.Projects = append(profile.Projects, ProfileProject{Name: a.Label, Description: description})
+		default:
+			if a.Label != "" {
+				warnings = append(warnings, fmt.Sprintf("achievement %q not mapped", a.Label))
+			}
+		}
+	}
+
+	return Extraction{Text: renderProfile(profile), Profile: profile, Warnings: warnings}, nil
+}
+
+// europassHTML converts a Europass description fragment to text.
+func europassHTML(fragment string, warnings *[]string) string {
+	if strings.TrimSpace(fragment) == "" {
+		return ""
+	}
+	text, err := extractHTMLText([]byte(fragment))
+	if err != nil {
+		*warnings = append(*warnings, fmt.Sprintf("unreadable description: %v", err))
+		return ""
+	}
+	return strings.TrimSpace(text)
+}
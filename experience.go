@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// monthPattern lists the month names and abbreviations in full, so words
+	// like "Marketing" or "Junior" aren't read as months
+	monthPattern   = `(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\b`
+	datePattern    = `(?:` + monthPattern + `\.?,?\s*(?:19|20)\d{2}|\d{1,2}[/.](?:19|20)\d{2}|(?:19|20)\d{2})`
+	presentPattern = `present|current|currently|now|today|date|ongoing`
+)
+
+var (
+	requiredYearsPattern = regexp.MustCompile(`(?i)(\d{1,2})\s*\+?\s*(?:-|–|to)?\s*(?:\d{1,2}\s*)?\+?\s*years?`)
+	sentenceSplitPattern = regexp.MustCompile(`[\n;•]|\.\s`)
+	dateRangePattern     = regexp.MustCompile(`(?i)\b(` + datePattern + `)\s*(?:-|–|—|to|until|till)\s*(` + datePattern + `|` + presentPattern + `)\b`)
+	presentDatePattern   = regexp.MustCompile(`(?i)^(` + presentPattern + `)$`)
+	monthYearPattern     = regexp.MustCompile(`(?i)^(` + monthPattern + `)\.?,?\s*(\d{4})$`)
+	numericDatePattern   = regexp.MustCompile(`^(\d{1,2})[/.](\d{4})$`)
+
+	educationPattern = regexp.MustCompile(`(?i)\b(university|college|school|academy|bachelor|master'?s|b\.?sc|m\.?sc|ph\.?d|degree|diploma|gpa|coursework)\b`)
+)
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// EmploymentRange is one dated entry found in the resume's work history.
+type EmploymentRange struct {
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Current bool     `json:"current,omitempty"`
+	Months  int      `json:"months"`
+	Text    string   `json:"text"`
+	Skills  []string `json:"skills,omitempty"`
+	start   int
+	end     int
+}
+
+// EmploymentGap is a stretch between merged employment ranges longer than
+// the configured threshold.
+type EmploymentGap struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Months int    `json:"months"`
+}
+
+// ExperienceSummary holds experience computed from work-history dates, so
+// the model doesn't have to do date arithmetic.
+type ExperienceSummary struct {
+	Ranges        []EmploymentRange  `json:"ranges"`
+	TotalYears    float64            `json:"total_years"`
+	RelevantYears float64            `json:"relevant_years"`
+	SkillYears    map[string]float64 `json:"skill_years,omitempty"`
+	Gaps          []EmploymentGap    `json:"gaps,omitempty"`
+}
+
+// requiredYears returns the smallest "N years" figure stated in the job
+// description, or 0 when none is stated.
+func requiredYears(jobDescription string) float64 {
+	var lowest float64
+	for _, m := range requiredYearsPattern.FindAllStringSubmatch(jobDescription, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n == 0 {
+			continue
+		}
+		if lowest == 0 || float64(n) < lowest {
+			lowest = float64(n)
+		}
+	}
+	return lowest
+}
+
+// candidateYears returns the total years of experience in the resume.
+func candidateYears(resumeText string, now time.Time) float64 {
+	return computeExperience(resumeText, nil, now, 0).TotalYears
+}
+
+// monthIndex counts months since year 0 so ranges can be compared and
+// subtracted directly.
+func monthIndex(year, month int) int {
+	return year*12 + month - 1
+}
+
+func formatMonth(index int) string {
+	return fmt.Sprintf("%04d-%02d", index/12, index%12+1)
+}
+
+// parseResumeDate parses one side of a date range. hasMonth is false for a
+// bare year.
+func parseResumeDate(s string) (year, month int, hasMonth, ok bool) {
+	s = strings.TrimSpace(s)
+	if m := monthYearPattern.FindStringSubmatch(s); m != nil {
+		name := strings.ToLower(m[1])
+		if len(name) < 3 {
+			return 0, 0, false, false
+		}
+		month, known := monthNames[name[:3]]
+		if !known {
+			return 0, 0, false, false
+		}
+		year, _ = strconv.Atoi(m[2])
+		return year, month, true, true
+	}
+	if m := numericDatePattern.FindStringSubmatch(s); m != nil {
+		month, _ = strconv.Atoi(m[1])
+		year, _ = strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			return 0, 0, false, false
+		}
+		return year, month, true, true
+	}
+	year, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, false, false
+	}
+	return year, 0, false, true
+}
+
+// parseDateRange turns a matched range into inclusive month indexes. Bare
+// years are taken from mid-year so "2019 - 2021" counts as two years.
+func parseDateRange(from, to string, now time.Time) (start, end int, current, ok bool) {
+	nowIndex := monthIndex(now.Year(), int(now.Month()))
+
+	sy, sm, sHasMonth, ok := parseResumeDate(from)
+	if !ok {
+		return 0, 0, false, false
+	}
+	if !sHasMonth {
+		sm = 7
+	}
+	start = monthIndex(sy, sm)
+
+	if presentDatePattern.MatchString(strings.TrimSpace(to)) {
+		end, current = nowIndex, true
+	} else {
+		ey, em, eHasMonth, ok := parseResumeDate(to)
+		if !ok {
+			return 0, 0, false, false
+		}
+		if !eHasMonth {
+			em = 6
+		}
+		end = monthIndex(ey, em)
+		if end < start && !sHasMonth && !eHasMonth && sy == ey {
+			// "2019 - 2019": the whole year
+			start, end = monthIndex(sy, 1), monthIndex(ey, 12)
+		}
+	}
+
+	if start > nowIndex || end < start {
+		return 0, 0, false, false
+	}
+	if end > nowIndex {
+		end = nowIndex
+	}
+	return start, end, current, true
+}
+
+type monthSpan struct{ start, end int }
+
+// mergeSpans merges overlapping and adjacent spans and returns them sorted.
+func mergeSpans(spans []monthSpan) []monthSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	sorted := append([]monthSpan(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+
+	merged := []monthSpan{sorted[0]}
+	for _, s := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end+1 {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func spanMonths(spans []monthSpan) int {
+	total := 0
+	for _, s := range mergeSpans(spans) {
+		total += s.end - s.start + 1
+	}
+	return total
+}
+
+func monthsToYears(months int) float64 {
+	return math.Round(float64(months)/12*10) / 10
+}
+
+// jdSkills returns the known skills mentioned in the job title or
+// description.
+func jdSkills(jobTitle, jobDescription string) []string {
+	seen := map[string]bool{}
+	var skills []string
+	for _, tok := range tokenize(jobTitle + "\n" + jobDescription) {
+		if knownSkills[tok] && !seen[tok] {
+			seen[tok] = true
+			skills = append(skills, tok)
+		}
+	}
+	return skills
+}
+
+// computeExperience extracts dated work-history entries from the resume,
+// merges overlapping ranges and computes total years, years relevant to the
+// given skills, per-skill years and gaps longer than gapMonths. Sections come
+// from segmentResume: entries under an education or certifications heading
+// are ignored, as are entries outside the experience section that read like
+// education.
+func computeExperience(resumeText string, skills []string, now time.Time, gapMonths int) *ExperienceSummary {
+	var lines, sectionOf []string
+	for _, s := range segmentResume(resumeText) {
+		if s.Heading != "" {
+			lines = append(lines, s.Heading)
+			sectionOf = append(sectionOf, s.Name)
+		}
+		for _, line := range s.Lines {
+			lines = append(lines, line)
+			sectionOf = append(sectionOf, s.Name)
+		}
+	}
+
+	type entry struct {
+		line     int
+		dateOnly bool
+		r        EmploymentRange
+	}
+	var entries []entry
+	for i, line := range lines {
+		section := sectionOf[i]
+		if section == sectionEducation || section == sectionCertifications {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		for _, m := range dateRangePattern.FindAllStringSubmatch(line, -1) {
+			start, end, current, ok := parseDateRange(m[1], m[2], now)
+			if !ok {
+				continue
+			}
+			if section != sectionExperience {
+				context := line
+				if i > 0 {
+					context = lines[i-1] + "\n" + line
+				}
+				if educationPattern.MatchString(context) {
+					continue
+				}
+			}
+			entries = append(entries, entry{line: i, dateOnly: isDateOnlyLine(line), r: EmploymentRange{
+				Start:   formatMonth(start),
+				End:     formatMonth(end),
+				Current: current,
+				Months:  end - start + 1,
+				Text:    trimmed,
+				start:   start,
+				end:     end,
+			}})
+		}
+	}
+
+	summary := &ExperienceSummary{}
+	if len(entries) == 0 {
+		return summary
+	}
+
+	wanted := map[string]bool{}
+	for _, s := range skills {
+		wanted[s] = true
+	}
+	var all, relevant []monthSpan
+	perSkill := map[string][]monthSpan{}
+	for i, e := range entries {
+		// an entry's block runs from its title up to the next entry's title.
+		// When the dates sit on a line of their own the title is the line
+		// before.
+		from := e.line
+		if e.dateOnly {
+			from = max(e.line-1, 0)
+		}
+		to := len(lines)
+		if i+1 < len(entries) {
+			next := entries[i+1]
+			to = next.line
+			if next.dateOnly {
+				to = next.line - 1
+			}
+			to = max(to, e.line+1)
+		}
+		block := strings.Join(lines[from:to], "\n")
+
+		span := monthSpan{e.r.start, e.r.end}
+		all = append(all, span)
+		isRelevant := false
+		seen := map[string]bool{}
+		for _, tok := range tokenize(block) {
+			if !knownSkills[tok] || seen[tok] {
+				continue
+			}
+			seen[tok] = true
+			e.r.Skills = append(e.r.Skills, displayTerm(tok))
+			if wanted[tok] {
+				isRelevant = true
+				perSkill[tok] = append(perSkill[tok], span)
+			}
+		}
+		if isRelevant {
+			relevant = append(relevant, span)
+		}
+		summary.Ranges = append(summary.Ranges, e.r)
+	}
+
+	summary.TotalYears = monthsToYears(spanMonths(all))
+	summary.RelevantYears = monthsToYears(spanMonths(relevant))
+	if len(perSkill) > 0 {
+		summary.SkillYears = map[string]float64{}
+		for skill, spans := range perSkill {
+			summary.SkillYears[displayTerm(skill)] = monthsToYears(spanMonths(spans))
+		}
+	}
+
+	if gapMonths > 0 {
+		merged := mergeSpans(all)
+		for i := 1; i < len(merged); i++ {
+			gap := merged[i].start - merged[i-1].end - 1
+			if gap > gapMonths {
+				summary.Gaps = append(summary.Gaps, EmploymentGap{
+					From:   formatMonth(merged[i-1].end + 1),
+					To:     formatMonth(merged[i].start - 1),
+					Months: gap,
+				})
+			}
+		}
+		nowIndex := monthIndex(now.Year(), int(now.Month()))
+		if last := merged[len(merged)-1]; nowIndex-last.end > gapMonths {
+			summary.Gaps = append(summary.Gaps, EmploymentGap{
+				From:   formatMonth(last.end + 1),
+				To:     "present",
+				Months: nowIndex - last.end,
+			})
+		}
+	}
+	return summary
+}
+
+// isDateOnlyLine reports whether a line holds little besides a date range.
+func isDateOnlyLine(line string) bool {
+	rest := strings.Trim(dateRangePattern.ReplaceAllString(line, ""), " \t|,()-–—")
+	return len(rest) < 4
+}
+
+var mustHaveMarkers = []string{"must", "required", "requirement", "essential", "mandatory", "need to have", "minimum"}
+
+// mustHaveSkills returns the known skills mentioned in sentences of the job
+// description that are phrased as hard requirements.
+func mustHaveSkills(jobDescription string) []string {
+	seen := map[string]bool{}
+	var skills []string
+	for _, sentence := range sentenceSplitPattern.Split(jobDescription, -1) {
+		lower := strings.ToLower(sentence)
+		marked := false
+		for _, marker := range mustHaveMarkers {
+			if strings.Contains(lower, marker) {
+				marked = true
+				break
+			}
+		}
+		if !marked {
+			continue
+		}
+		for _, tok := range tokenize(sentence) {
+			if knownSkills[tok] && !seen[tok] {
+				seen[tok] = true
+				skills = append(skills, tok)
+			}
+		}
+	}
+	return skills
+}
+
+// experienceContext renders the computed experience for the agent input.
+func experienceContext(summary *ExperienceSummary) string {
+	var b strings.Builder
+	b.WriteString("Computed Experience (from work-history dates, use these figures instead of your own date arithmetic):\n")
+	fmt.Fprintf(&b, "- Total years: %.1f\n", summary.TotalYears)
+	fmt.Fprintf(&b, "- Years relevant to the job: %.1f\n", summary.RelevantYears)
+	skills := make([]string, 0, len(summary.SkillYears))
+	for skill := range summary.SkillYears {
+		skills = append(skills, skill)
+	}
+	sort.Strings(skills)
+	for _, skill := range skills {
+		fmt.Fprintf(&b, "- %s: %.1f years\n", skill, summary.SkillYears[skill])
+	}
+	for _, gap := range summary.Gaps {
+		fmt.Fprintf(&b, "- Employment gap: %s to %s (%d months)\n", gap.From, gap.To, gap.Months)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateRangeMonths(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text       string
+		start, end string
+	}{
+		{"Jan 2019 - Mar 2021", "2019-01", "2021-03"},
+		{"January 2019 – September 2021", "2019-01", "2021-09"},
+		{"Sept. 2018 to June 2020", "2018-09", "2020-06"},
+		{"Dec, 2022 - present", "2022-12", "2025-03"},
+		{"05/2017 - 11/2019", "2017-05", "2019-11"},
+		// bare years run mid-year to mid-year
+		{"Marketing 2019 - 2021", "2019-07", "2021-06"},
+		{"Junior 2016 – 2018", "2016-07", "2018-06"},
+		{"Junior Developer 2016 – 2018", "2016-07", "2018-06"},
+		{"Mayor's office 2015 - 2017", "2015-07", "2017-06"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			m := dateRangePattern.FindStringSubmatch(tt.text)
+			if m == nil {
+				t.Fatalf("no date range found in %q", tt.text)
+			}
+			start, end, _, ok := parseDateRange(m[1], m[2], now)
+			if !ok {
+				t.Fatalf("parseDateRange(%q, %q) failed", m[1], m[2])
+			}
+			if got := formatMonth(start); got != tt.start {
+				t.Errorf("start = %s, want %s (matched %q)", got, tt.start, m[0])
+			}
+			if got := formatMonth(end); got != tt.end {
+				t.Errorf("end = %s, want %s (matched %q)", got, tt.end, m[0])
+			}
+		})
+	}
+}
+
+// computeExperience reads sections through segmentResume, the same headings
+// the structured profile uses.
+func TestComputeExperienceSections(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		resume string
+		ranges []string
+	}{
+		{
+			name:   "experience section",
+			resume: "Work History\nBackend Engineer, Acme\nJan 2019 - Dec 2020",
+			ranges: []string{"2019-01..2020-12"},
+		},
+		{
+			name:   "education section ignored",
+			resume: "Experience\nEngineer, Acme\nJan 2019 - Dec 2020\n\nEducation\nState Polytechnic\nSep 2014 - Jun 2018",
+			ranges: []string{"2019-01..2020-12"},
+		},
+		{
+			name:   "certifications section ignored",
+			resume: "Certifications\nAWS Solutions Architect\nMar 2021 - Mar 2024\n\nExperience\nEngineer, Acme\nJan 2019 - Dec 2020",
+			ranges: []string{"2019-01..2020-12"},
+		},
+		{
+			name:   "combined education heading ignored",
+			resume: "Education & Certifications\nCKA\nJan 2022 - Jan 2025",
+		},
+		{
+			name:   "no headings",
+			resume: "Engineer, Acme\nJan 2019 - Dec 2020",
+			ranges: []string{"2019-01..2020-12"},
+		},
+		{
+			name:   "education outside a heading ignored",
+			resume: "B.Sc. Computer Science, University of Lagos\n2014 - 2018\nEngineer, Acme\nJan 2019 - Dec 2020",
+			ranges: []string{"2019-01..2020-12"},
+		},
+		{
+			name:   "education words inside the experience section kept",
+			resume: "Experience\nTeaching Assistant, University of Lagos\nJan 2017 - Dec 2018",
+			ranges: []string{"2017-01..2018-12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range computeExperience(tt.resume, nil, now, 0).Ranges {
+				got = append(got, r.Start+".."+r.End)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.ranges, ",") {
+				t.Errorf("ranges = %q, want %q", got, tt.ranges)
+			}
+		})
+	}
+}
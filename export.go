@@ -0,0 +1,306 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+	"github.com/streadway/amqp"
+)
+
+// exportQueue receives on-demand export requests: {"session_id": "..."}.
+const exportQueue = "exports"
+
+// exportTopSkills is how many relevant skills an export row lists.
+const exportTopSkills = 5
+
+var exportHeader = []string{"Filename", "Email", "Score", "Recommendation", "Top skills", "Missing skills", "Error"}
+
+// exportRow is one candidate's row, in exportHeader order. Score is empty
+// for failed files.
+func exportRow(result AnalysesResult) []string {
+	score := ""
+	if !result.IsErrorResult {
+		score = strconv.Itoa(result.MatchScore)
+	}
+	filename := result.Filename
+	if result.Archive != "" {
+		filename = result.Archive + "/" + result.Filename
+	}
+	skills := result.RelevantSkills
+	if len(skills) > exportTopSkills {
+		skills = skills[:exportTopSkills]
+	}
+	errorText := result.Error
+	if result.KnockedOut {
+		errorText = "knocked out: " + result.KnockoutReason
+	}
+	return []string{
+		filename,
+		result.CandidateEmail,
+		score,
+		result.Recomendation,
+		strings.Join(skills, "; "),
+		strings.Join(result.MissingSkills, "; "),
+		errorText,
+	}
+}
+
+func renderCSV(results []AnalysesResult) ([]byte, error) {
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	w.Write(exportHeader)
+	for _, result := range results {
+		row := exportRow(result)
+		for i, cell := range row {
+			row[i] = csvSafe(cell)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return out.Bytes(), w.Error()
+}
+
+// csvSafe stops spreadsheets treating text from resumes as a formula.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// xlsxParts are the fixed parts of a one-sheet workbook; the sheet itself
+// is written by renderXLSX. Style 1 is the bold header.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Results" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`},
+}
+
+// renderXLSX writes the export as a minimal workbook with inline strings,
+// which spreadsheet apps read without a shared string table.
+func renderXLSX(results []AnalysesResult) ([]byte, error) {
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	writeRow := func(n int, cells []string, header bool) {
+		fmt.Fprintf(&sheet, `<row r="%d">`, n)
+		for i, cell := range cells {
+			ref := fmt.Sprintf("%c%d", 'A'+i, n)
+			switch {
+			case header:
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr" s="1"><is><t>`, ref)
+			case exportHeader[i] == "Score" && cell != "":
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, cell)
+				continue
+			default:
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			}
+			xml.EscapeText(&sheet, []byte(cell))
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	writeRow(1, exportHeader, true)
+	for i, result := range results {
+		writeRow(i+2, exportRow(result), false)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	parts := append(xlsxParts[:len(xlsxParts):len(xlsxParts)], struct{ name, body string }{"xl/worksheets/sheet1.xml", sheet.String()})
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// exportReconnectDelay is how long the export consumer waits before
+// reconnecting to RabbitMQ.
+const exportReconnectDelay = 5 * time.Second
+
+// exportLocation picks where a session's exports are stored: next to its
+// resumes, in the store they were uploaded to, or with the session reports
+// (ReportStorage) when that store can't be written to. It returns the
+// provider and the key prefix.
+func exportLocation(ctx context.Context, workerConfig *WorkerConfig, sessionID uuid.UUID) (provider, prefix string, err error) {
+	resumes, err := retry(3, func() ([]database.Resume, error) {
+		return workerConfig.DB.GetResumesBySession(ctx, sessionID)
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load resumes: %w", err)
+	}
+	for _, resume := range resumes {
+		store, err := workerConfig.Storage.For(resume.StorageProvider)
+		if err != nil || resume.ObjectKey == "" {
+			continue
+		}
+		if _, ok := store.(BlobWriter); ok {
+			return resume.StorageProvider, path.Join(path.Dir(resume.ObjectKey), "exports", sessionID.String()), nil
+		}
+	}
+	if workerConfig.ReportStorage != "" {
+		return workerConfig.ReportStorage, path.Join("exports", sessionID.String()), nil
+	}
+	return "", "", permanentError{errors.New("no storage for exports: the session's resumes are in a read-only store and report storage is disabled")}
+}
+
+// publishExports renders the CSV and XLSX exports and uploads them, see
+// exportLocation, returning the provider and keys they were stored under.
+func publishExports(ctx context.Context, workerConfig *WorkerConfig, sessionID uuid.UUID, results []AnalysesResult) (string, []string, error) {
+	provider, prefix, err := exportLocation(ctx, workerConfig, sessionID)
+	if err != nil {
+		return "", nil, err
+	}
+	csvData, err := renderCSV(results)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render csv: %w", err)
+	}
+	xlsxData, err := renderXLSX(results)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render xlsx: %w", err)
+	}
+	var keys []string
+	for _, upload := range []struct {
+		key, contentType string
+		data             []byte
+	}{
+		{path.Join(prefix, "results.csv"), "text/csv; charset=utf-8", csvData},
+		{path.Join(prefix, "results.xlsx"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", xlsxData},
+	} {
+		_, err := retry(3, func() (any, error) {
+			return nil, workerConfig.Storage.Put(ctx, provider, upload.key, upload.contentType, upload.data)
+		})
+		if err != nil {
+			return provider, keys, fmt.Errorf("failed to upload %s: %w", upload.key, err)
+		}
+		keys = append(keys, upload.key)
+	}
+	return provider, keys, nil
+}
+
+// exportWorker handles on-demand export requests for sessions analysed
+// earlier, loading their saved results. A lost connection to RabbitMQ is
+// retried rather than taking the worker down.
+func exportWorker(workerConfig *WorkerConfig, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		err := consumeExports(workerConfig)
+		log.Printf("⚠️ Export consumer stopped: %v. Reconnecting in %s", err, exportReconnectDelay)
+		time.Sleep(exportReconnectDelay)
+	}
+}
+
+// consumeExports handles export requests until the connection or channel
+// closes. Requests are acknowledged once handled; one that fails for a
+// reason a retry might fix is requeued once.
+func consumeExports(workerConfig *WorkerConfig) error {
+	conn, err := amqp.Dial(workerConfig.RABBITMQUrl)
+	if err != nil {
+		return fmt.Errorf("error dialling rabbitmq: %w", err)
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("error connecting to rabbitmq channel: %w", err)
+	}
+	defer ch.Close()
+	if _, err := ch.QueueDeclare(exportQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	// unacknowledged requests stay on the queue for other consumers
+	if err := ch.Qos(1, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	msgs, err := ch.Consume(exportQueue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("error consuming rabbitmq message: %w", err)
+	}
+
+	for msg := range msgs {
+		handleExportRequest(workerConfig, msg)
+	}
+	return errors.New("delivery channel closed")
+}
+
+func handleExportRequest(workerConfig *WorkerConfig, msg amqp.Delivery) {
+	var request struct {
+		SessionID uuid.UUID `json:"session_id"`
+	}
+	if err := json.Unmarshal(msg.Body, &request); err != nil || request.SessionID == uuid.Nil {
+		log.Printf("invalid export request %q: %v", msg.Body, err)
+		msg.Reject(false)
+		return
+	}
+
+	provider, keys, err := exportSession(context.Background(), workerConfig, request.SessionID)
+	if err != nil && !msg.Redelivered && !errors.As(err, new(permanentError)) {
+		log.Printf("error exporting session_id: %v, requeueing. err: %v", request.SessionID, err)
+		msg.Nack(false, true)
+		return
+	}
+	update := map[string]any{
+		"session_id": request.SessionID,
+		"status":     "export_ready",
+		"message":    "export ready",
+		"timestamp":  time.Now(),
+	}
+	if err != nil {
+		log.Printf("error exporting session_id: %v. err: %v", request.SessionID, err)
+		update["status"], update["message"] = "export_failed", "export failed"
+	} else {
+		update["storage_provider"], update["keys"] = provider, keys
+	}
+	if err := publishSessionUpdate(workerConfig.RabbitConn, request.SessionID.String(), update); err != nil {
+		log.Println("failed to publish update:", err)
+	}
+	msg.Ack(false)
+}
+
+// exportSession exports a session's saved results.
+func exportSession(ctx context.Context, workerConfig *WorkerConfig, sessionID uuid.UUID) (string, []string, error) {
+	resultsJSON, err := workerConfig.DB.GetAnalysesResultsBySession(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, permanentError{errors.New("session has no analyses results")}
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load analyses results: %w", err)
+	}
+	var results []AnalysesResult
+	if err := json.Unmarshal(resultsJSON, &results); err != nil {
+		return "", nil, permanentError{fmt.Errorf("failed to parse analyses results: %w", err)}
+	}
+	return publishExports(ctx, workerConfig, sessionID, results)
+}
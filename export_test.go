@@ -0,0 +1,205 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		result AnalysesResult
+		want   []string
+	}{
+		{
+			name: "scored",
+			result: AnalysesResult{
+				Filename:       "jane.pdf",
+				CandidateEmail: "jane@example.com",
+				MatchScore:     82,
+				Recomendation:  "Interview",
+				RelevantSkills: []string{"Go", "SQL", "Docker", "AWS", "Kafka", "Redis"},
+				MissingSkills:  []string{"Kubernetes", "Terraform"},
+			},
+			want: []string{"jane.pdf", "jane@example.com", "82", "Interview", "Go; SQL; Docker; AWS; Kafka", "Kubernetes; Terraform", ""},
+		},
+		{
+			name:   "archive entry",
+			result: AnalysesResult{Archive: "batch.zip", Filename: "cv/john.docx", MatchScore: 0},
+			want:   []string{"batch.zip/cv/john.docx", "", "0", "", "", "", ""},
+		},
+		{
+			name:   "knocked out",
+			result: AnalysesResult{Filename: "sam.pdf", MatchScore: 55, KnockedOut: true, KnockoutReason: "no work permit"},
+			want:   []string{"sam.pdf", "", "55", "", "", "", "knocked out: no work permit"},
+		},
+		{
+			name:   "failed",
+			result: AnalysesResult{Filename: "locked.pdf", IsErrorResult: true, Error: "file is encrypted"},
+			want:   []string{"locked.pdf", "", "", "", "", "", "file is encrypted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportRow(tt.result)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exportRow = %q, want %q", got, tt.want)
+			}
+			if len(got) != len(exportHeader) {
+				t.Errorf("exportRow has %d cells, header has %d", len(got), len(exportHeader))
+			}
+		})
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Go; SQL", "Go; SQL"},
+		{"82", "82"},
+		{`=HYPERLINK("http://evil","x")`, `'=HYPERLINK("http://evil","x")`},
+		{"+1 555 0100", "'+1 555 0100"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+	}
+	for _, tt := range tests {
+		if got := csvSafe(tt.in); got != tt.want {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	results := []AnalysesResult{
+		{Filename: "jane.pdf", CandidateEmail: "jane@example.com", MatchScore: 82, Recomendation: `=cmd|' /C calc'!A0`},
+		{Filename: "locked.pdf", IsErrorResult: true, Error: "-failed, twice"},
+	}
+	data, err := renderCSV(results)
+	if err != nil {
+		t.Fatalf("renderCSV: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("rendered CSV doesn't parse: %v", err)
+	}
+	want := [][]string{
+		exportHeader,
+		{"jane.pdf", "jane@example.com", "82", `'=cmd|' /C calc'!A0`, "", "", ""},
+		{"locked.pdf", "", "", "", "", "", "'-failed, twice"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV records = %q, want %q", records, want)
+	}
+}
+
+// xlsxSheet is the part of a worksheet the export tests read.
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			S      string `xml:"s,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+			F      string `xml:"f"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestRenderXLSX(t *testing.T) {
+	results := []AnalysesResult{
+		{Filename: "jane.pdf", CandidateEmail: "jane@example.com", MatchScore: 82, RelevantSkills: []string{"Go", "C++ & <XML>"}},
+		{Filename: "formula.pdf", MatchScore: 40, Recomendation: "=SUM(1,2)"},
+		{Filename: "locked.pdf", IsErrorResult: true, Error: "file is encrypted"},
+	}
+	data, err := renderXLSX(results)
+	if err != nil {
+		t.Fatalf("renderXLSX: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("XLSX isn't a valid zip: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = body
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		body, ok := parts[name]
+		if !ok {
+			t.Errorf("XLSX is missing %s", name)
+			continue
+		}
+		if err := xml.Unmarshal(body, new(struct{})); err != nil {
+			t.Errorf("%s isn't well-formed XML: %v", name, err)
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("parse sheet: %v", err)
+	}
+	if len(sheet.Rows) != len(results)+1 {
+		t.Fatalf("sheet has %d rows, want %d", len(sheet.Rows), len(results)+1)
+	}
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d has r=%d", i, row.R)
+		}
+		if len(row.Cells) != len(exportHeader) {
+			t.Errorf("row %d has %d cells, want %d", i+1, len(row.Cells), len(exportHeader))
+		}
+		for _, cell := range row.Cells {
+			if cell.F != "" {
+				t.Errorf("cell %s is a formula: %q", cell.R, cell.F)
+			}
+		}
+	}
+
+	header := sheet.Rows[0].Cells
+	if header[0].R != "A1" || header[0].Inline != "Filename" || header[0].S != "1" || header[6].R != "G1" || header[6].Inline != "Error" {
+		t.Errorf("header row = %+v, want bold inline strings Filename..Error in A1:G1", header)
+	}
+
+	jane := sheet.Rows[1].Cells
+	if jane[2].R != "C2" || jane[2].T != "" || jane[2].V != "82" {
+		t.Errorf("score cell = %+v, want numeric 82 in C2", jane[2])
+	}
+	if jane[4].T != "inlineStr" || jane[4].Inline != "Go; C++ & <XML>" {
+		t.Errorf("skills cell = %+v, want inline string %q", jane[4], "Go; C++ & <XML>")
+	}
+	if rec := sheet.Rows[2].Cells[3]; rec.T != "inlineStr" || rec.Inline != "=SUM(1,2)" {
+		t.Errorf("recommendation cell = %+v, want the formula text as an inline string", rec)
+	}
+	locked := sheet.Rows[3].Cells
+	if locked[2].T != "inlineStr" || locked[2].V != "" || locked[2].Inline != "" {
+		t.Errorf("failed file's score cell = %+v, want an empty string", locked[2])
+	}
+	if locked[6].Inline != "file is encrypted" {
+		t.Errorf("error cell = %q, want %q", locked[6].Inline, "file is encrypted")
+	}
+	if !strings.Contains(string(parts["xl/workbook.xml"]), `<sheet name="Results" sheetId="1" r:id="rId1"/>`) {
+		t.Error("workbook doesn't declare the Results sheet")
+	}
+}
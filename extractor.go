@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// extractorVersion is recorded with every extraction. Bump it when
+// extractors change their output, so stored text is extracted again.
+const extractorVersion = "1"
+
+// Extraction is the structured output of an Extractor. The text itself is
+// left out of the stored results.
+type Extraction struct {
+	Text string `json:"-"`
+	// PageCount is 0 for formats without fixed pages.
+	PageCount int      `json:"page_count,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Extractor string   `json:"extractor"`
+	// Version is extractorVersion at the time of extraction.
+	Version string `json:"version,omitempty"`
+	// OCR is set when the text was recognised from page images;
+	// OCRConfidence is tesseract's mean word confidence, 0-1.
+	OCR           bool    `json:"ocr,omitempty"`
+	OCRConfidence float64 `json:"ocr_confidence,omitempty"`
+	// Quality is filled in once the text has been assessed.
+	Quality *TextQuality `json:"quality,omitempty"`
+	// Profile is set by extractors for structured formats, which map onto
+	// the profile directly instead of it being parsed back out of Text.
+	Profile *ResumeProfile `json:"-"`
+}
+
+// Extractor pulls text out of one family of document formats.
+type Extractor interface {
+	Name() string
+	MimeTypes() []string
+	// Signatures are magic byte prefixes identifying the format when the
+	// MIME type is unknown.
+	Signatures() [][]byte
+	// Priority decides between extractors claiming the same MIME type;
+	// the highest wins.
+	Priority() int
+	Extract(data []byte) (Extraction, error)
+}
+
+// funcExtractor adapts a plain extraction function to Extractor.
+type funcExtractor struct {
+	name       string
+	mimeTypes  []string
+	signatures [][]byte
+	priority   int
+	extract    func(data []byte) (Extraction, error)
+}
+
+func (e funcExtractor) Name() string                            { return e.name }
+func (e funcExtractor) MimeTypes() []string                     { return e.mimeTypes }
+func (e funcExtractor) Signatures() [][]byte                    { return e.signatures }
+func (e funcExtractor) Priority() int                           { return e.priority }
+func (e funcExtractor) Extract(data []byte) (Extraction, error) { return e.extract(data) }
+
+// textOnly wraps an extraction function that has nothing to report besides
+// the text.
+func textOnly(extract func(data []byte) (string, error)) func(data []byte) (Extraction, error) {
+	return func(data []byte) (Extraction, error) {
+		text, err := extract(data)
+		return Extraction{Text: text}, err
+	}
+}
+
+// builtinExtractors are registered in every ExtractorRegistry. pdf-plain is
+// the old unstructured PDF extractor, kept at a lower priority so it can be
+// compared against the layout-aware one through EXTRACTOR_SHADOW or switched
+// back on through EXTRACTOR_OVERRIDES.
+func builtinExtractors() []Extractor {
+	return []Extractor{
+		funcExtractor{
+			name:      "text",
+			mimeTypes: []string{mimeText},
+			extract: textOnly(func(data []byte) (string, error) {
+				return string(bytes.TrimPrefix(data, utf8BOM)), nil
+			}),
+		},
+		funcExtractor{
+			name:       "pdf",
+			mimeTypes:  []string{mimePDF},
+			signatures: [][]byte{pdfMagic},
+			priority:   10,
+			extract: func(data []byte) (Extraction, error) {
+				return extractPDFText(bytes.NewReader(data))
+			},
+		},
+		funcExtractor{
+			name:       "pdf-plain",
+			mimeTypes:  []string{mimePDF},
+			signatures: [][]byte{pdfMagic},
+			extract: func(data []byte) (Extraction, error) {
+				return extractPDFPlainText(bytes.NewReader(data))
+			},
+		},
+		funcExtractor{
+			name:      "docx",
+			mimeTypes: []string{mimeDocx},
+			priority:  10,
+			extract: textOnly(func(data []byte) (string, error) {
+				return extractDocxText(bytes.NewReader(data))
+			}),
+		},
+		funcExtractor{
+			name:       "doc",
+			mimeTypes:  []string{mimeDoc},
+			signatures: [][]byte{oleMagic},
+			priority:   10,
+			extract:    textOnly(extractDocText),
+		},
+		funcExtractor{
+			name:       "rtf",
+			mimeTypes:  []string{mimeRTF},
+			signatures: [][]byte{rtfMagic},
+			priority:   10,
+			extract:    textOnly(extractRTFText),
+		},
+		funcExtractor{
+			name:      "odt",
+			mimeTypes: []string{mimeODT},
+			priority:  10,
+			extract:   textOnly(extractODTText),
+		},
+		funcExtractor{
+			name:      "html",
+			mimeTypes: []string{mimeHTML},
+			priority:  10,
+			extract:   textOnly(extractHTMLText),
+		},
+		funcExtractor{
+			name:      "markdown",
+			mimeTypes: []string{mimeMarkdown},
+			priority:  10,
+			extract:   textOnly(extractMarkdownText),
+		},
+		funcExtractor{
+			name:      "jsonresume",
+			mimeTypes: []string{mimeJSON},
+			priority:  10,
+			extract:   extractJSONResume,
+		},
+		funcExtractor{
+			name:      "europass",
+			mimeTypes: []string{mimeXML},
+			priority:  10,
+			extract:   extractEuropassCV,
+		},
+	}
+}
+
+// ExtractorRegistry picks the extractor for a document. Overrides pin a MIME
+// type to a named extractor; shadows run a second extractor on the same
+// document and log how its output compares, without affecting the result.
+type ExtractorRegistry struct {
+	extractors []Extractor
+	overrides  map[string]string
+	shadows    map[string]string
+	// ocr, when set, handles image uploads and re-reads PDFs whose text
+	// layer is missing
+	ocr *OCREngine
+	// sandbox, when set, isolates extractors from the worker process
+	sandbox *Sandbox
+}
+
+func NewExtractorRegistry(extractors ...Extractor) *ExtractorRegistry {
+	r := &ExtractorRegistry{overrides: map[string]string{}, shadows: map[string]string{}}
+	for _, e := range extractors {
+		r.Register(e)
+	}
+	return r
+}
+
+// Register adds an extractor, replacing any with the same name.
+func (r *ExtractorRegistry) Register(e Extractor) {
+	for i, existing := range r.extractors {
+		if existing.Name() == e.Name() {
+			r.extractors = append(r.extractors[:i], r.extractors[i+1:]...)
+			break
+		}
+	}
+	r.extractors = append(r.extractors, e)
+	sort.SliceStable(r.extractors, func(i, j int) bool {
+		return r.extractors[i].Priority() > r.extractors[j].Priority()
+	})
+}
+
+func (r *ExtractorRegistry) Lookup(name string) (Extractor, bool) {
+	for _, e := range r.extractors {
+		if e.Name() == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Override makes the named extractor handle mime regardless of priority.
+func (r *ExtractorRegistry) Override(mime, name string) error {
+	if _, ok := r.Lookup(name); !ok {
+		return fmt.Errorf("unknown extractor %q", name)
+	}
+	r.overrides[normalizeMime(mime)] = name
+	return nil
+}
+
+// Shadow runs the named extractor alongside the chosen one for mime.
+func (r *ExtractorRegistry) Shadow(mime, name string) error {
+	if _, ok := r.Lookup(name); !ok {
+		return fmt.Errorf("unknown extractor %q", name)
+	}
+	r.shadows[normalizeMime(mime)] = name
+	return nil
+}
+
+// EnableOCR registers image extraction and the scanned PDF fallback.
+func (r *ExtractorRegistry) EnableOCR(engine *OCREngine) {
+	r.ocr = engine
+	r.Register(funcExtractor{
+		name:       "ocr-image",
+		mimeTypes:  []string{mimePNG, mimeJPEG, mimeTIFF},
+		signatures: [][]byte{pngMagic, jpegMagic, tiffMagicLE, tiffMagicBE},
+		priority:   10,
+		extract:    engine.Image,
+	})
+}
+
+// SetSandbox runs extractors through sandbox.
+func (r *ExtractorRegistry) SetSandbox(sandbox *Sandbox) {
+	r.sandbox = sandbox
+}
+
+// For returns the extractor for a document: an override for its MIME type,
+// else the highest priority extractor claiming the type, else the highest
+// priority one whose signature matches the content.
+func (r *ExtractorRegistry) For(mime string, data []byte) (Extractor, bool) {
+	if name, ok := r.overrides[mime]; ok {
+		return r.Lookup(name)
+	}
+	for _, e := range r.extractors {
+		for _, m := range e.MimeTypes() {
+			if m == mime {
+				return e, true
+			}
+		}
+	}
+	for _, e := range r.extractors {
+		for _, sig := range e.Signatures() {
+			if len(sig) > 0 && bytes.HasPrefix(data, sig) {
+				return e, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Extract runs the extractor for mime on data. label identifies the
+// document in shadow comparison logs.
+func (r *ExtractorRegistry) Extract(label, mime string, data []byte) (Extraction, error) {
+	e, ok := r.For(mime, data)
+	if !ok {
+		return Extraction{}, fmt.Errorf("unsupported file type: %s", mime)
+	}
+	extraction, err := r.run(e, data)
+	if err != nil {
+		return extraction, err
+	}
+	if r.ocr != nil && mime == mimePDF && needsOCR(extraction) {
+		extraction = r.ocrFallback(label, extraction, data)
+	}
+	extraction.Text = normalizeText(extraction.Text)
+
+	if name, ok := r.shadows[mime]; ok && name != e.Name() {
+		shadow, _ := r.Lookup(name)
+		shadowExtraction, err := r.run(shadow, data)
+		if err != nil {
+			log.Printf("🔬 shadow extractor %s failed on %s: %v", name, label, err)
+		} else {
+			log.Printf("🔬 extractor %s vs shadow %s on %s: %d vs %d chars, %.2f term overlap",
+				e.Name(), name, label, len(extraction.Text), len(shadowExtraction.Text),
+				termOverlap(extraction.Text, shadowExtraction.Text))
+		}
+	}
+	return extraction, nil
+}
+
+// ocrFallback re-reads a PDF without a usable text layer through OCR,
+// keeping the original extraction if OCR doesn't do better.
+func (r *ExtractorRegistry) ocrFallback(label string, extraction Extraction, data []byte) Extraction {
+	log.Printf("🔎 %s has little or no text layer, running OCR", label)
+	recognized, err := r.ocr.PDF(data)
+	if err != nil {
+		log.Printf("⚠️ OCR failed for %s: %v", label, err)
+		extraction.Warnings = append(extraction.Warnings, fmt.Sprintf("OCR fallback failed: %v", err))
+		return extraction
+	}
+	if len(strings.TrimSpace(recognized.Text)) <= len(strings.TrimSpace(extraction.Text)) {
+		extraction.Warnings = append(extraction.Warnings, "OCR fallback found no additional text")
+		return extraction
+	}
+	recognized.Extractor = extraction.Extractor + "+ocr"
+	recognized.Version = extraction.Version
+	recognized.PageCount = extraction.PageCount
+	if extraction.PageCount > r.ocr.MaxPages {
+		recognized.Warnings = append(recognized.Warnings, fmt.Sprintf("only the first %d of %d pages were OCRed", r.ocr.MaxPages, extraction.PageCount))
+	}
+	recognized.Language = detectLanguage(recognized.Text)
+	return recognized
+}
+
+func (r *ExtractorRegistry) run(e Extractor, data []byte) (Extraction, error) {
+	var extraction Extraction
+	var err error
+	if r.sandbox != nil {
+		extraction, err = r.sandbox.Run(e, data)
+	} else {
+		extraction, err = e.Extract(data)
+	}
+	extraction.Extractor = e.Name()
+	extraction.Version = extractorVersion
+	if err != nil {
+		return extraction, err
+	}
+	if extraction.Language == "" {
+		extraction.Language = detectLanguage(extraction.Text)
+	}
+	return extraction, nil
+}
+
+// normalizeText tidies extracted text before anything reads or stores it:
+// Unix line endings, no trailing spaces, at most one blank line in a row and
+// no NULs, which Postgres text columns reject.
+func normalizeText(text string) string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "").Replace(text)
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank++; blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// termOverlap is the Jaccard similarity of two texts' term sets.
+func termOverlap(a, b string) float64 {
+	setA := termCounts(tokenize(a))
+	setB := termCounts(tokenize(b))
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+	shared := 0
+	for t := range setA {
+		if setB[t] > 0 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// parseExtractorMap parses "mime=extractor,mime=extractor" settings.
+func parseExtractorMap(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		mime, name, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(mime) == "" || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected mime=extractor", pair)
+		}
+		out[strings.TrimSpace(mime)] = strings.TrimSpace(name)
+	}
+	return out, nil
+}
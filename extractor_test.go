@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractSamples runs each format's extractor over its sample under
+// testdata/samples and compares the text with testdata/golden/<sample>.txt.
+func TestExtractSamples(t *testing.T) {
+	tests := []struct {
+		sample  string
+		extract func(data []byte) (string, error)
+	}{
+		{"resume.doc", extractDocText},
+		{"resume.rtf", extractRTFText},
+		{"resume.odt", extractODTText},
+		{"resume.html", extractHTMLText},
+		{"resume.md", extractMarkdownText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sample, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "samples", tt.sample))
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := tt.extract(data)
+			if err != nil {
+				t.Fatalf("extracting %s: %v", tt.sample, err)
+			}
+			checkGolden(t, filepath.Join("testdata", "golden", tt.sample+".txt"), text)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fuzz targets cover everything that parses untrusted uploads. Their
+// malformed seed corpora live in testdata/fuzz/<target>; the sample
+// documents under testdata/samples are added as seeds too.
+
+// fuzzMaxInput keeps individual inputs small enough that slow but correct
+// parsing isn't mistaken for a hang.
+const fuzzMaxInput = 1 << 20
+
+func addSampleSeeds(f *testing.F) {
+	f.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "samples", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzExtractors(f *testing.F) {
+	addSampleSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > fuzzMaxInput {
+			return
+		}
+		for _, e := range builtinExtractors() {
+			e.Extract(data)
+		}
+	})
+}
+
+func FuzzResolveMime(f *testing.F) {
+	addSampleSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > fuzzMaxInput {
+			return
+		}
+		for _, declared := range []string{"", mimePDF, mimeDocx, mimeDoc} {
+			resolveMime(declared, data)
+		}
+	})
+}
+
+func FuzzExpandArchive(f *testing.F) {
+	addSampleSeeds(f)
+	limits := ArchiveLimits{MaxFiles: 20, MaxBytes: 4 << 20, MaxDepth: 2}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > fuzzMaxInput {
+			return
+		}
+		entries, err := expandArchive(data, limits)
+		if err != nil {
+			return
+		}
+		if len(entries) > limits.MaxFiles+1 {
+			t.Errorf("expandArchive returned %d entries, limit is %d", len(entries), limits.MaxFiles)
+		}
+		var total int64
+		for _, entry := range entries {
+			total += int64(len(entry.Data))
+		}
+		if total > limits.MaxBytes {
+			t.Errorf("expandArchive returned %d bytes, limit is %d", total, limits.MaxBytes)
+		}
+	})
+}
+
+func FuzzParseEmail(f *testing.F) {
+	addSampleSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > fuzzMaxInput {
+			return
+		}
+		parseEmailMessage(mimeEmail, data)
+		parseEmailMessage(mimeMsg, data)
+	})
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// htmlSkipElements hold scripts, styles, page metadata and embedded media
+// rather than resume text.
+var htmlSkipElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Iframe: true, atom.Object: true,
+	atom.Canvas: true, atom.Select: true,
+}
+
+// htmlBlockElements start and end a line.
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Fieldset: true,
+	atom.Figcaption: true, atom.Figure: true, atom.Footer: true, atom.Form: true,
+	atom.Header: true, atom.Hr: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.Section: true, atom.Table: true, atom.Ul: true, atom.Caption: true,
+	atom.Summary: true, atom.Details: true,
+}
+
+// htmlParagraphElements are blocks followed by a blank line.
+var htmlParagraphElements = map[atom.Atom]bool{
+	atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Pre: true,
+}
+
+// extractHTMLText returns the visible text of an HTML page (e.g. a job
+// board's resume export). Block elements become lines, list items get a
+// "- " prefix, table rows become "cell | cell" lines and link targets follow
+// their link text. The character set comes from the BOM or <meta> tags.
+func extractHTMLText(data []byte) (string, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), "")
+	if err != nil {
+		return "", fmt.Errorf("failed to decode html: %w", err)
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	w := &htmlWalker{}
+	w.walk(doc)
+	w.newline()
+	text := strings.TrimSpace(strings.Join(w.lines, "\n"))
+	return blankLinesPattern.ReplaceAllString(text, "\n\n"), nil
+}
+
+type htmlWalker struct {
+	lines []string
+	line  strings.Builder
+	pre   int
+	// cells seen in each open table row
+	cells []int
+}
+
+// newline ends the current line, if it has any text.
+func (w *htmlWalker) newline() {
+	// an empty list item leaves just its marker
+	if text := strings.TrimSpace(w.line.String()); text != "" && text != "-" {
+		w.lines = append(w.lines, text)
+	}
+	w.line.Reset()
+}
+
+func (w *htmlWalker) paragraph() {
+	w.newline()
+	if n := len(w.lines); n > 0 && w.lines[n-1] != "" {
+		w.lines = append(w.lines, "")
+	}
+}
+
+func (w *htmlWalker) text(s string) {
+	if w.pre > 0 {
+		for i, part := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.lines = append(w.lines, strings.TrimRight(w.line.String(), " \t"))
+				w.line.Reset()
+			}
+			w.line.WriteString(part)
+		}
+		return
+	}
+	s = xmlSpacePattern.ReplaceAllString(strings.ReplaceAll(s, "\u00a0", " "), " ")
+	if s == " " || s == "" {
+		if w.line.Len() > 0 && !strings.HasSuffix(w.line.String(), " ") {
+			w.line.WriteString(" ")
+		}
+		return
+	}
+	if strings.HasSuffix(w.line.String(), " ") || w.line.Len() == 0 {
+		s = strings.TrimLeft(s, " ")
+	}
+	w.line.WriteString(s)
+}
+
+func (w *htmlWalker) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	case html.ElementNode:
+		if htmlSkipElements[n.DataAtom] {
+			return
+		}
+		if n.DataAtom == atom.Input && htmlAttr(n, "type") == "hidden" {
+			return
+		}
+		if strings.Contains(strings.ReplaceAll(strings.ToLower(htmlAttr(n, "style")), " ", ""), "display:none") || htmlHasAttr(n, "hidden") {
+			return
+		}
+	}
+
+	switch a := n.DataAtom; {
+	case a == atom.Br:
+		w.newline()
+		return
+	case a == atom.Li:
+		w.newline()
+		w.line.WriteString("- ")
+	case a == atom.Tr:
+		w.newline()
+		w.cells = append(w.cells, 0)
+	case a == atom.Td || a == atom.Th:
+		if c := len(w.cells); c > 0 {
+			if w.cells[c-1] > 0 {
+				w.line.WriteString(" | ")
+			}
+			w.cells[c-1]++
+		}
+	case a == atom.Pre:
+		w.paragraph()
+		w.pre++
+	case htmlParagraphElements[a]:
+		w.paragraph()
+	case htmlBlockElements[a]:
+		w.newline()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+
+	switch a := n.DataAtom; {
+	case a == atom.A:
+		href := strings.TrimSpace(htmlAttr(n, "href"))
+		if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "mailto:") {
+			display := strings.TrimPrefix(href, "mailto:")
+			if !strings.Contains(w.line.String(), display) {
+				w.line.WriteString(" (" + display + ")")
+			}
+		}
+	case a == atom.Li:
+		w.newline()
+	case a == atom.Tr:
+		w.cells = w.cells[:len(w.cells)-1]
+		w.newline()
+	case a == atom.Pre:
+		w.pre--
+		w.paragraph()
+	case htmlParagraphElements[a]:
+		w.paragraph()
+	case htmlBlockElements[a]:
+		w.newline()
+	}
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func htmlHasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	componentLLM        = "llm"
+	componentKeywords   = "keywords"
+	componentExperience = "experience"
+	componentMustHave   = "must_have"
+)
+
+// ScoreWeights controls how much each signal contributes to the final
+// match score. Weights are relative; they don't need to sum to 1.
+type ScoreWeights map[string]float64
+
+func defaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		componentLLM:        0.6,
+		componentKeywords:   0.2,
+		componentExperience: 0.1,
+		componentMustHave:   0.1,
+	}
+}
+
+// parseScoreWeights parses "llm=0.6,keywords=0.2,..." on top of the default
+// weights.
+func parseScoreWeights(spec string) (ScoreWeights, error) {
+	weights := defaultScoreWeights()
+	if strings.TrimSpace(spec) == "" {
+		return weights, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q, expected name=value", pair)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := weights[name]; !known {
+			return nil, fmt.Errorf("unknown score component %q", name)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, value)
+		}
+		weights[name] = w
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all score weights are zero")
+	}
+	return weights, nil
+}
+
+type ScoreComponent struct {
+	Name   string  `json:"name"`
+	Score  int     `json:"score"`
+	Weight float64 `json:"weight"`
+	// Applicable is false when the JD gave nothing to measure against, in
+	// which case the component is left out of the final score.
+	Applicable bool   `json:"applicable"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// ScoreBreakdown records every input to the final score so it can be
+// explained and audited later.
+type ScoreBreakdown struct {
+	Components       []ScoreComponent `json:"components"`
+	RequiredYears    float64          `json:"required_years"`
+	CandidateYears   float64          `json:"candidate_years"`
+	MustHavesMet     []string         `json:"must_haves_met"`
+	MustHavesMissing []string         `json:"must_haves_missing"`
+	FinalScore       int              `json:"final_score"`
+}
+
+// hybridScore blends the agent's score with deterministic signals computed
+// from the JD and resume text, and stores the breakdown on the result.
+func hybridScore(result *AnalysesResult, weights ScoreWeights, jobTitle, jobDescription, resumeText string) {
+	breakdown := &ScoreBreakdown{}
+
+	breakdown.Components = append(breakdown.Components, ScoreComponent{
+		Name:       componentLLM,
+		Score:      result.MatchScore,
+		Weight:     weights[componentLLM],
+		Applicable: true,
+	})
+
+	keywords := matchTerms(jdTerms(jobTitle, jobDescription), resumeText)
+	breakdown.Components = append(breakdown.Components, ScoreComponent{
+		Name:       componentKeywords,
+		Score:      keywords.Score,
+		Weight:     weights[componentKeywords],
+		Applicable: len(keywords.Matched)+len(keywords.Missing) > 0,
+		Detail:     fmt.Sprintf("%d of %d terms matched", len(keywords.Matched), len(keywords.Matched)+len(keywords.Missing)),
+	})
+
+	breakdown.RequiredYears = requiredYears(jobDescription)
+	breakdown.CandidateYears = candidateYears(resumeText, time.Now())
+	experience := ScoreComponent{
+		Name:       componentExperience,
+		Weight:     weights[componentExperience],
+		Applicable: breakdown.RequiredYears > 0,
+	}
+	if experience.Applicable {
+		experience.Score = int(math.Round(100 * math.Min(1, breakdown.CandidateYears/breakdown.RequiredYears)))
+		experience.Detail = fmt.Sprintf("%.1f of %.0f required years", breakdown.CandidateYears, breakdown.RequiredYears)
+	}
+	breakdown.Components = append(breakdown.Components, experience)
+
+	resumeTerms := termCounts(tokenize(resumeText))
+	for _, skill := range mustHaveSkills(jobDescription) {
+		if resumeTerms[skill] > 0 {
+			breakdown.MustHavesMet = append(breakdown.MustHavesMet, displayTerm(skill))
+		} else {
+			breakdown.MustHavesMissing = append(breakdown.MustHavesMissing, displayTerm(skill))
+		}
+	}
+	mustHaves := len(breakdown.MustHavesMet) + len(breakdown.MustHavesMissing)
+	mustHave := ScoreComponent{
+		Name:       componentMustHave,
+		Weight:     weights[componentMustHave],
+		Applicable: mustHaves > 0,
+	}
+	if mustHave.Applicable {
+		mustHave.Score = 100 * len(breakdown.MustHavesMet) / mustHaves
+		mustHave.Detail = fmt.Sprintf("%d of %d must-haves met", len(breakdown.MustHavesMet), mustHaves)
+	}
+	breakdown.Components = append(breakdown.Components, mustHave)
+
+	var weighted, total float64
+	for _, c := range breakdown.Components {
+		if !c.Applicable || c.Weight == 0 {
+			continue
+		}
+		weighted += c.Weight * float64(c.Score)
+		total += c.Weight
+	}
+	breakdown.FinalScore = result.MatchScore
+	if total > 0 {
+		breakdown.FinalScore = int(math.Round(weighted / total))
+	}
+
+	result.MatchScore = breakdown.FinalScore
+	result.ScoreBreakdown = breakdown
+}
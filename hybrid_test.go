@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseScoreWeights(t *testing.T) {
+	tests := []struct {
+		spec string
+		want ScoreWeights
+		ok   bool
+	}{
+		{"", defaultScoreWeights(), true},
+		{"llm=1, keywords = 0.5", ScoreWeights{componentLLM: 1, componentKeywords: 0.5, componentExperience: 0.1, componentMustHave: 0.1}, true},
+		{"experience=0,must_have=0", ScoreWeights{componentLLM: 0.6, componentKeywords: 0.2, componentExperience: 0, componentMustHave: 0}, true},
+		{"llm", nil, false},
+		{"style=1", nil, false},
+		{"llm=-0.5", nil, false},
+		{"llm=high", nil, false},
+		{"llm=NaN", nil, false},
+		{"llm=Inf", nil, false},
+		{"llm=0,keywords=0,experience=0,must_have=0", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseScoreWeights(tt.spec)
+			if (err == nil) != tt.ok {
+				t.Fatalf("parseScoreWeights(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseScoreWeights(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+			for name, w := range tt.want {
+				if got[name] != w {
+					t.Errorf("weight %s = %v, want %v", name, got[name], w)
+				}
+			}
+		})
+	}
+}
+
+func TestHybridScore(t *testing.T) {
+	const (
+		jobTitle = "Go Engineer"
+		resume   = "Go engineer\nExperience\nEngineer, Acme\nJan 2016 - Dec 2019\nBuilt Go services"
+	)
+	defaults := defaultScoreWeights()
+	llmOnly := ScoreWeights{componentLLM: 1}
+	tests := []struct {
+		name           string
+		jobDescription string
+		weights        ScoreWeights
+		// scores of the experience and must-have components, -1 when not
+		// applicable
+		experience, mustHave int
+	}{
+		{"all components", "Must have Go. 4+ years of experience.", defaults, 100, 100},
+		{"must-have missing", "Must have Go and Docker. 8+ years of experience.", defaults, 50, 50},
+		{"nothing to measure renormalises", "We write Go.", defaults, -1, -1},
+		{"zero weights leave components out", "Must have Docker. 8 years.", llmOnly, 50, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := AnalysesResult{MatchScore: 80}
+			hybridScore(&result, tt.weights, jobTitle, tt.jobDescription, resume)
+			b := result.ScoreBreakdown
+			if b == nil || len(b.Components) != 4 {
+				t.Fatalf("breakdown = %+v, want four components", b)
+			}
+
+			keywords := matchTerms(jdTerms(jobTitle, tt.jobDescription), resume).Score
+			scores := map[string]int{componentLLM: 80, componentKeywords: keywords, componentExperience: tt.experience, componentMustHave: tt.mustHave}
+			var weighted, total float64
+			for _, c := range b.Components {
+				want := scores[c.Name]
+				if applicable := want >= 0; c.Applicable != applicable {
+					t.Errorf("%s applicable = %v, want %v", c.Name, c.Applicable, applicable)
+					continue
+				}
+				if !c.Applicable {
+					continue
+				}
+				if c.Score != want {
+					t.Errorf("%s score = %d, want %d", c.Name, c.Score, want)
+				}
+				if c.Weight != tt.weights[c.Name] {
+					t.Errorf("%s weight = %v, want %v", c.Name, c.Weight, tt.weights[c.Name])
+				}
+				weighted += tt.weights[c.Name] * float64(want)
+				total += tt.weights[c.Name]
+			}
+			want := int(math.Round(weighted / total))
+			if b.FinalScore != want || result.MatchScore != want {
+				t.Errorf("FinalScore, MatchScore = %d, %d, want %d", b.FinalScore, result.MatchScore, want)
+			}
+		})
+	}
+}
+
+func TestHybridScoreWeightedBlend(t *testing.T) {
+	// 3 parts llm at 80 and 1 part must-have at 0; keywords carry no weight
+	// and experience has no requirement to measure
+	result := AnalysesResult{MatchScore: 80}
+	hybridScore(&result, ScoreWeights{componentLLM: 3, componentMustHave: 1}, "Engineer", "Must have Docker.", "Go engineer")
+	if result.MatchScore != 60 {
+		t.Errorf("MatchScore = %d, want 60", result.MatchScore)
+	}
+}
@@ -26,3 +26,15 @@ func (q *Queries) CreateOrUpdateAnalysesResults(ctx context.Context, arg CreateO
 	_, err := q.db.ExecContext(ctx, createOrUpdateAnalysesResults, arg.Results, arg.SessionID)
 	return err
 }
+
+const getAnalysesResultsBySession = `-- name: GetAnalysesResultsBySession :one
+SELECT results FROM analyses_results
+WHERE session_id = $1
+`
+
+func (q *Queries) GetAnalysesResultsBySession(ctx context.Context, sessionID uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, getAnalysesResultsBySession, sessionID)
+	var results json.RawMessage
+	err := row.Scan(&results)
+	return results, err
+}
@@ -1,11 +1,20 @@
 package database
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AnalysesResult struct {
+	ID        uuid.UUID
+	Results   json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SessionID uuid.UUID
+}
+
 type Resume struct {
 	ID               uuid.UUID
 	OriginalFilename string
@@ -18,3 +27,38 @@ type Resume struct {
 	CreatedAt        time.Time
 	SessionID        uuid.UUID
 }
+
+type ResumeProfile struct {
+	ResumeID  uuid.UUID
+	SessionID uuid.UUID
+	Profile   json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ResumeText struct {
+	ResumeID         uuid.UUID
+	SessionID        uuid.UUID
+	Checksum         string
+	Text             string
+	Extractor        string
+	ExtractorVersion string
+	Warnings         json.RawMessage
+	Extraction       json.RawMessage
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type Session struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	Name               string
+	UserID             uuid.UUID
+	Status             string
+	JobTitle           string
+	JobDescription     string
+	KnockoutRules      json.RawMessage
+	ReportHtmlKey      string
+	ReportPdfKey       string
+	ConsistencySamples int32
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const upsertResumeProfile = `-- name: UpsertResumeProfile :exec
+INSERT INTO resume_profiles (
+resume_id, session_id, profile)
+VALUES ( $1, $2, $3)
+ON CONFLICT (resume_id)
+DO UPDATE SET
+    profile = EXCLUDED.profile,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertResumeProfileParams struct {
+	ResumeID  uuid.UUID
+	SessionID uuid.UUID
+	Profile   json.RawMessage
+}
+
+func (q *Queries) UpsertResumeProfile(ctx context.Context, arg UpsertResumeProfileParams) error {
+	_, err := q.db.ExecContext(ctx, upsertResumeProfile, arg.ResumeID, arg.SessionID, arg.Profile)
+	return err
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const getResumeText = `-- name: GetResumeText :one
+SELECT resume_id, session_id, checksum, text, extractor, extractor_version, warnings, extraction, created_at, updated_at FROM resume_texts
+WHERE resume_id = $1
+`
+
+func (q *Queries) GetResumeText(ctx context.Context, resumeID uuid.UUID) (ResumeText, error) {
+	row := q.db.QueryRowContext(ctx, getResumeText, resumeID)
+	var i ResumeText
+	err := row.Scan(
+		&i.ResumeID,
+		&i.SessionID,
+		&i.Checksum,
+		&i.Text,
+		&i.Extractor,
+		&i.ExtractorVersion,
+		&i.Warnings,
+		&i.Extraction,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertResumeText = `-- name: UpsertResumeText :exec
+INSERT INTO resume_texts (
+resume_id, session_id, checksum, text, extractor, extractor_version, warnings, extraction)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (resume_id)
+DO UPDATE SET
+    checksum = EXCLUDED.checksum,
+    text = EXCLUDED.text,
+    extractor = EXCLUDED.extractor,
+    extractor_version = EXCLUDED.extractor_version,
+    warnings = EXCLUDED.warnings,
+    extraction = EXCLUDED.extraction,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertResumeTextParams struct {
+	ResumeID         uuid.UUID
+	SessionID        uuid.UUID
+	Checksum         string
+	Text             string
+	Extractor        string
+	ExtractorVersion string
+	Warnings         json.RawMessage
+	Extraction       json.RawMessage
+}
+
+func (q *Queries) UpsertResumeText(ctx context.Context, arg UpsertResumeTextParams) error {
+	_, err := q.db.ExecContext(ctx, upsertResumeText,
+		arg.ResumeID,
+		arg.SessionID,
+		arg.Checksum,
+		arg.Text,
+		arg.Extractor,
+		arg.ExtractorVersion,
+		arg.Warnings,
+		arg.Extraction,
+	)
+	return err
+}
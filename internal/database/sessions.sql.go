@@ -2,10 +2,46 @@ package database
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const getSession = `-- name: GetSession :one
+SELECT id, created_at, name, user_id, status, job_title, job_description, COALESCE(knockout_rules, '[]')::jsonb AS knockout_rules, consistency_samples FROM sessions
+WHERE id = $1
+`
+
+type GetSessionRow struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	Name               string
+	UserID             uuid.UUID
+	Status             string
+	JobTitle           string
+	JobDescription     string
+	KnockoutRules      json.RawMessage
+	ConsistencySamples int32
+}
+
+func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (GetSessionRow, error) {
+	row := q.db.QueryRowContext(ctx, getSession, id)
+	var i GetSessionRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Name,
+		&i.UserID,
+		&i.Status,
+		&i.JobTitle,
+		&i.JobDescription,
+		&i.KnockoutRules,
+		&i.ConsistencySamples,
+	)
+	return i, err
+}
+
 const updateSessionStatus = `-- name: UpdateSessionStatus :exec
 UPDATE sessions 
 SET status=$1
@@ -21,3 +57,20 @@ func (q *Queries) UpdateSessionStatus(ctx context.Context, arg UpdateSessionStat
 	_, err := q.db.ExecContext(ctx, updateSessionStatus, arg.Status, arg.ID)
 	return err
 }
+
+const updateSessionReport = `-- name: UpdateSessionReport :exec
+UPDATE sessions
+SET report_html_key=$1, report_pdf_key=$2
+WHERE id=$3
+`
+
+type UpdateSessionReportParams struct {
+	ReportHtmlKey string
+	ReportPdfKey  string
+	ID            uuid.UUID
+}
+
+func (q *Queries) UpdateSessionReport(ctx context.Context, arg UpdateSessionReportParams) error {
+	_, err := q.db.ExecContext(ctx, updateSessionReport, arg.ReportHtmlKey, arg.ReportPdfKey, arg.ID)
+	return err
+}
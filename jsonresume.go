@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var errNotJSONResume = errors.New("not a JSON Resume document")
+
+// jsonResumeSections are the top-level JSON Resume keys mapped onto
+// ResumeProfile; anything else is reported as a warning.
+var jsonResumeSections = map[string]bool{
+	"$schema": true, "meta": true,
+	"basics": true, "work": true, "education": true, "skills": true,
+	"certificates": true, "projects": true, "languages": true,
+}
+
+// extractJSONResume maps a JSON Resume (https://jsonresume.org/schema)
+// document straight onto the profile, and renders it as text for the agent.
+func extractJSONResume(data []byte) (Extraction, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return Extraction{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if top["basics"] == nil && top["work"] == nil && top["education"] == nil {
+		return Extraction{}, errNotJSONResume
+	}
+
+	var doc struct {
+		ResumeProfile
+		// pre-1.0 schema names
+		Work []struct {
+			ProfileWork
+			Company string `json:"company"`
+		} `json:"work"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Extraction{}, fmt.Errorf("invalid JSON Resume: %w", err)
+	}
+	profile := doc.ResumeProfile
+	profile.Work = nil
+	for _, w := range doc.Work {
+		if w.Name == "" {
+			w.Name = w.Company
+		}
+		profile.Work = append(profile.Work, w.ProfileWork)
+	}
+
+	var unmapped []string
+	for key := range top {
+		if !jsonResumeSections[key] {
+			unmapped = append(unmapped, key)
+		}
+	}
+	extraction := Extraction{Text: renderProfile(&profile), Profile: &profile}
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		extraction.Warnings = append(extraction.Warnings, "sections not mapped: "+strings.Join(unmapped, ", "))
+	}
+	return extraction, nil
+}
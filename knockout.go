@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+const (
+	knockoutKeyword       = "keyword"
+	knockoutCertification = "certification"
+	knockoutWorkPermit    = "work_permit"
+	knockoutMinDegree     = "min_degree"
+	knockoutMinYears      = "min_years"
+
+	knockoutCheckDeterministic = "deterministic"
+	knockoutCheckModel         = "model"
+
+	scoringMethodKnockout = "knockout"
+)
+
+// KnockoutRule is a hard requirement attached to a session. A candidate who
+// fails any rule is knocked out and skips the full analysis.
+type KnockoutRule struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Value is the country, certification, degree level or year count.
+	Value string `json:"value"`
+	// Values are accepted alternatives, e.g. spellings of a certification.
+	Values      []string `json:"values,omitempty"`
+	Check       string   `json:"check,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+func (rule KnockoutRule) terms() []string {
+	terms := append([]string(nil), rule.Values...)
+	if rule.Value != "" {
+		terms = append(terms, rule.Value)
+	}
+	return terms
+}
+
+func (rule KnockoutRule) String() string {
+	if rule.Description != "" {
+		return rule.Description
+	}
+	return fmt.Sprintf("%s: %s", rule.Type, strings.Join(rule.terms(), " / "))
+}
+
+// validate rejects rules the worker can't evaluate. A rule that can't be
+// checked would otherwise pass every candidate.
+func (rule KnockoutRule) validate() error {
+	switch rule.Type {
+	case knockoutKeyword, knockoutCertification, knockoutWorkPermit:
+		for _, term := range rule.terms() {
+			if strings.TrimSpace(term) != "" {
+				return rule.validateCheck()
+			}
+		}
+		return fmt.Errorf("%s rule has no value", rule.Type)
+	case knockoutMinDegree:
+		if _, ok := degreeLevel(rule.Value); !ok {
+			return fmt.Errorf("unrecognised degree level %q", rule.Value)
+		}
+	case knockoutMinYears:
+		years, err := strconv.ParseFloat(strings.TrimSpace(rule.Value), 64)
+		if err != nil || years < 0 {
+			return fmt.Errorf("invalid year count %q", rule.Value)
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	return rule.validateCheck()
+}
+
+func (rule KnockoutRule) validateCheck() error {
+	switch rule.Check {
+	case "", knockoutCheckDeterministic, knockoutCheckModel:
+		return nil
+	}
+	return fmt.Errorf("unknown check %q", rule.Check)
+}
+
+type knockoutOutcome struct {
+	Rule   KnockoutRule
+	Passed bool
+	Reason string
+}
+
+var degreeLevels = []struct {
+	level   int
+	name    string
+	pattern *regexp.Regexp
+	// aliases are short names a rule may use that pattern doesn't match on
+	// their own.
+	aliases []string
+}{
+	{5, "doctorate", regexp.MustCompile(`(?i)\b(ph\.?\s?d|doctorate|doctor of|d\.?phil)(?:$|[^a-z])`), []string{"doctoral"}},
+	{4, "master", regexp.MustCompile(`(?i)\b(master'?s?\s+(?:degree|of|in)|m\.?sc|m\.s\.|m\.?eng|m\.a\.|mba|m\.?tech)(?:$|[^a-z])`), []string{"masters", "master's", "ms", "ma", "postgraduate"}},
+	{3, "bachelor", regexp.MustCompile(`(?i)\b(bachelor'?s?|b\.?sc|b\.s\.|b\.?eng|b\.a\.|b\.?tech|undergraduate degree)(?:$|[^a-z])`), []string{"bs", "ba", "undergraduate"}},
+	{2, "associate", regexp.MustCompile(`(?i)\b(associate'?s? degree|a\.?a\.?s|hnd|ond|diploma)(?:$|[^a-z])`), []string{"associates", "associate's"}},
+	{1, "high school", regexp.MustCompile(`(?i)\b(high school|secondary school|ged|a-levels?|waec|ssce)(?:$|[^a-z])`), nil},
+}
+
+// degreeLevel resolves a rule's degree name, e.g. "Masters" or "PhD in CS".
+func degreeLevel(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, d := range degreeLevels {
+		if name == d.name || slices.Contains(d.aliases, name) || d.pattern.MatchString(name) {
+			return d.level, true
+		}
+	}
+	return 0, false
+}
+
+// highestDegree returns the level of the highest degree mentioned in text.
+func highestDegree(text string) (int, string) {
+	for _, d := range degreeLevels {
+		if d.pattern.MatchString(text) {
+			return d.level, d.name
+		}
+	}
+	return 0, ""
+}
+
+// clauseSeparator splits a line into clauses so "Nigerian citizen, will
+// require visa sponsorship for the UK" is judged part by part.
+var clauseSeparator = regexp.MustCompile(`(?i)[;,]|\s+(?:but|however|although)\s+`)
+
+// sponsorshipPattern matches a clause saying the candidate still needs
+// permission to work: sponsorship, a visa they require, or one they are
+// seeking.
+var sponsorshipPattern = regexp.MustCompile(`(?i)\b(sponsor(?:ship|ed|ing)?|(?:requir|need)\w*\s+(?:a\s+|an\s+)?(?:\w+\s+)?(?:visa|work permit|permit)|(?:seeking|applying for|looking for|awaiting)\s+(?:\w+\s+){0,2}(?:visa|work permit|permit|authori[sz]ation|right to work|residency))\b`)
+
+// noSponsorshipPattern matches negated sponsorship, e.g. "no sponsorship
+// required" or "does not need a visa".
+var noSponsorshipPattern = regexp.MustCompile(`(?i)\b(?:no|not|without|never|don'?t|doesn'?t|won'?t)\s+(?:\w+\s+){0,2}(?:requir|need|sponsor)`)
+
+// workAuthorizationPattern builds the positive phrasings of authorization
+// to work in one of the countries: "authorized to work in X", "right to
+// work in X", "X citizen", "citizen of X" and "Citizenship: X".
+func workAuthorizationPattern(countries []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(countries))
+	for _, country := range countries {
+		if country = strings.TrimSpace(country); country != "" {
+			quoted = append(quoted, regexp.QuoteMeta(country))
+		}
+	}
+	country := `(?:the\s+)?(?:` + strings.Join(quoted, "|") + `)(?:$|[^\pL\pN])`
+	return regexp.MustCompile(`(?i)` +
+		`(?:authori[sz]ed|eligible|entitled|permitted|cleared)\s+to\s+work\s+in\s+` + country +
+		`|right\s+to\s+work\s+in\s+` + country +
+		`|(?:^|[^\pL\pN])(?:` + strings.Join(quoted, "|") + `)\s+(?:citizen|citizenship|national|passport|permanent\s+resident|green\s+card|work\s+permit|work\s+visa|right\s+to\s+work)` +
+		`|(?:citizen|national|permanent\s+resident)\s+of\s+` + country +
+		`|(?:citizenship|nationality|work\s+authori[sz]ation|work\s+permit|right\s+to\s+work|residency)\s*[:\-–]\s*` + country)
+}
+
+// statedWorkAuthorization returns the clause of text that positively states
+// authorization to work in one of the countries. Clauses asking for
+// sponsorship or a visa never count, even when they name the country.
+func statedWorkAuthorization(text string, countries []string) (string, bool) {
+	if len(countries) == 0 {
+		return "", false
+	}
+	pattern := workAuthorizationPattern(countries)
+	for _, line := range strings.Split(text, "\n") {
+		for _, clause := range clauseSeparator.Split(line, -1) {
+			if sponsorshipPattern.MatchString(clause) && !noSponsorshipPattern.MatchString(clause) {
+				continue
+			}
+			if pattern.MatchString(clause) {
+				return strings.TrimSpace(clause), true
+			}
+		}
+	}
+	return "", false
+}
+
+func containsTerm(text, term string) bool {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return false
+	}
+	pattern := `(?i)(^|[^\pL\pN])` + regexp.QuoteMeta(term) + `($|[^\pL\pN])`
+	matched, err := regexp.MatchString(pattern, text)
+	return err == nil && matched
+}
+
+// checkKnockoutRule evaluates a rule against the resume without a model.
+// Rules are validated when the session loads, so rule values parse here.
+func checkKnockoutRule(rule KnockoutRule, resumeText string) knockoutOutcome {
+	outcome := knockoutOutcome{Rule: rule}
+	switch rule.Type {
+	case knockoutKeyword, knockoutCertification:
+		for _, term := range rule.terms() {
+			if containsTerm(resumeText, term) {
+				outcome.Passed = true
+				outcome.Reason = fmt.Sprintf("found %q", term)
+				return outcome
+			}
+		}
+		outcome.Reason = fmt.Sprintf("none of %q found in resume", rule.terms())
+
+	case knockoutWorkPermit:
+		if clause, ok := statedWorkAuthorization(resumeText, rule.terms()); ok {
+			outcome.Passed = true
+			outcome.Reason = fmt.Sprintf("work authorization stated: %q", clause)
+			return outcome
+		}
+		outcome.Reason = fmt.Sprintf("no work authorization for %s stated in resume", strings.Join(rule.terms(), " / "))
+
+	case knockoutMinDegree:
+		required, _ := degreeLevel(rule.Value)
+		level, name := highestDegree(resumeText)
+		outcome.Passed = level >= required
+		if name == "" {
+			name = "no degree"
+		}
+		outcome.Reason = fmt.Sprintf("highest degree found: %s, required: %s", name, rule.Value)
+
+	case knockoutMinYears:
+		required, _ := strconv.ParseFloat(strings.TrimSpace(rule.Value), 64)
+		years := candidateYears(resumeText, time.Now())
+		outcome.Passed = years >= required
+		outcome.Reason = fmt.Sprintf("%.1f years found, %.0f required", years, required)
+
+	default:
+		outcome.Reason = fmt.Sprintf("unknown rule type %q", rule.Type)
+	}
+	return outcome
+}
+
+type modelKnockoutResponse struct {
+	Results []struct {
+		ID     string `json:"id"`
+		Passed bool   `json:"passed"`
+		Reason string `json:"reason"`
+	} `json:"results"`
+}
+
+// checkKnockoutRulesWithModel asks the cheap knockout model to evaluate all
+// model-checked rules in a single call.
+func checkKnockoutRulesWithModel(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, rules []KnockoutRule, resumeText string) ([]knockoutOutcome, error) {
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal knockout rules: %w", err)
+	}
+
+	agentSession, err := workerConfig.AgentSessionService.Create(ctx, &session.CreateRequest{
+		AppName:   workerConfig.KnockoutAgentName,
+		UserID:    currentSession.UserID.String(),
+		SessionID: fmt.Sprintf("%s-knockout-%d", currentSession.ID, time.Now().UnixNano()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create knockout session: %w", err)
+	}
+	defer workerConfig.AgentSessionService.Delete(ctx, &session.DeleteRequest{
+		AppName:   workerConfig.KnockoutAgentName,
+		UserID:    agentSession.Session.UserID(),
+		SessionID: agentSession.Session.ID(),
+	})
+
+	msg := fmt.Sprintf("Rules:\n%s\n\nResume:\n%s", rulesJSON, resumeText)
+	output, err := retry(2, func() (string, error) {
+		return runAgent(ctx, workerConfig.KnockoutRunner, agentSession.Session.UserID(), agentSession.Session.ID(), msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp modelKnockoutResponse
+	if err := json.Unmarshal([]byte(CleanJson(output)), &resp); err != nil {
+		return nil, fmt.Errorf("json unmarshal error: %w", err)
+	}
+	byID := map[string]knockoutOutcome{}
+	for _, r := range resp.Results {
+		byID[r.ID] = knockoutOutcome{Passed: r.Passed, Reason: r.Reason}
+	}
+
+	outcomes := make([]knockoutOutcome, 0, len(rules))
+	for _, rule := range rules {
+		outcome, ok := byID[rule.ID]
+		if !ok {
+			return nil, fmt.Errorf("model returned no verdict for rule %q", rule.ID)
+		}
+		outcome.Rule = rule
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
+}
+
+// applyKnockoutRules checks the session's rules and returns a knocked out
+// result for the first failed rule, or nil when the candidate passes.
+// Deterministic rules run first so the model call is skipped when a cheap
+// check already fails.
+func applyKnockoutRules(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, resumeText string) (*AnalysesResult, error) {
+	var modelRules []KnockoutRule
+	for i, rule := range currentSession.KnockoutRules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.Check == knockoutCheckModel {
+			modelRules = append(modelRules, rule)
+			continue
+		}
+		if outcome := checkKnockoutRule(rule, resumeText); !outcome.Passed {
+			return knockedOutResult(outcome, resumeText), nil
+		}
+	}
+	if len(modelRules) == 0 {
+		return nil, nil
+	}
+
+	outcomes, err := checkKnockoutRulesWithModel(ctx, workerConfig, currentSession, modelRules, resumeText)
+	if err != nil {
+		return nil, err
+	}
+	for _, outcome := range outcomes {
+		if !outcome.Passed {
+			return knockedOutResult(outcome, resumeText), nil
+		}
+	}
+	return nil, nil
+}
+
+func knockedOutResult(outcome knockoutOutcome, resumeText string) *AnalysesResult {
+	rule := outcome.Rule
+	return &AnalysesResult{
+		CandidateEmail: emailPattern.FindString(resumeText),
+		MatchScore:     0,
+		Summary:        fmt.Sprintf("Knocked out by rule %q: %s", rule.String(), outcome.Reason),
+		Recomendation:  "Not recommended: does not meet a mandatory requirement.",
+		Method:         scoringMethodKnockout,
+		KnockedOut:     true,
+		FailedRule:     &rule,
+		KnockoutReason: outcome.Reason,
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import "testing"
+
+func TestCheckKnockoutRuleWorkPermit(t *testing.T) {
+	uk := KnockoutRule{Type: knockoutWorkPermit, Value: "UK", Values: []string{"United Kingdom", "British"}}
+	tests := []struct {
+		name   string
+		resume string
+		passed bool
+	}{
+		{"authorized to work", "Authorized to work in the UK", true},
+		{"right to work", "Full right to work in the United Kingdom", true},
+		{"citizen", "British citizen", true},
+		{"citizen of", "Citizen of the United Kingdom", true},
+		{"labelled", "Nationality: British\nLocation: London", true},
+		{"no sponsorship needed", "UK citizen, no sponsorship required", true},
+		{"other country citizen needing sponsorship", "Nigerian citizen, will require visa sponsorship for the UK", false},
+		{"sponsorship same clause", "Authorized to work in the UK with sponsorship", false},
+		{"needs a visa", "Will need a work visa to work in the United Kingdom", false},
+		{"seeking", "Seeking right to work in the UK", false},
+		{"other country", "Authorized to work in the US but open to relocating to the UK", false},
+		{"country mentioned only", "Software Engineer, London, UK", false},
+		{"country inside a word", "Ukrainian citizen", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkKnockoutRule(uk, tt.resume)
+			if got.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v (%s)", got.Passed, tt.passed, got.Reason)
+			}
+		})
+	}
+}
+
+func TestCheckKnockoutRule(t *testing.T) {
+	resume := "Jane Doe\nB.Sc. Computer Science, 2014\nAWS Certified Solutions Architect\n\nExperience\nBackend Engineer, Acme\nJan 2015 - Dec 2019\n"
+	tests := []struct {
+		name   string
+		rule   KnockoutRule
+		passed bool
+	}{
+		{"keyword found", KnockoutRule{Type: knockoutKeyword, Value: "backend"}, true},
+		{"keyword missing", KnockoutRule{Type: knockoutKeyword, Value: "kotlin"}, false},
+		{"keyword inside a word", KnockoutRule{Type: knockoutKeyword, Value: "end"}, false},
+		{"certification alternative", KnockoutRule{Type: knockoutCertification, Value: "AWS SAA", Values: []string{"AWS Certified Solutions Architect"}}, true},
+		{"certification missing", KnockoutRule{Type: knockoutCertification, Value: "CISSP"}, false},
+		{"degree met", KnockoutRule{Type: knockoutMinDegree, Value: "bachelor"}, true},
+		{"degree below", KnockoutRule{Type: knockoutMinDegree, Value: "Masters"}, false},
+		{"degree rule with subject", KnockoutRule{Type: knockoutMinDegree, Value: "PhD in CS"}, false},
+		{"years met", KnockoutRule{Type: knockoutMinYears, Value: "4"}, true},
+		{"years short", KnockoutRule{Type: knockoutMinYears, Value: "6"}, false},
+		// rules loadSession would reject must not pass candidates either
+		{"unknown type", KnockoutRule{Type: "clearance", Value: "SC"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkKnockoutRule(tt.rule, resume)
+			if got.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v (%s)", got.Passed, tt.passed, got.Reason)
+			}
+		})
+	}
+}
+
+func TestKnockoutRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule KnockoutRule
+		ok   bool
+	}{
+		{KnockoutRule{Type: knockoutKeyword, Value: "go"}, true},
+		{KnockoutRule{Type: knockoutCertification, Values: []string{"CKA"}}, true},
+		{KnockoutRule{Type: knockoutWorkPermit, Value: "UK", Check: knockoutCheckModel}, true},
+		{KnockoutRule{Type: knockoutMinDegree, Value: "Masters"}, true},
+		{KnockoutRule{Type: knockoutMinDegree, Value: "MBA"}, true},
+		{KnockoutRule{Type: knockoutMinDegree, Value: "PhD in CS"}, true},
+		{KnockoutRule{Type: knockoutMinYears, Value: "2.5"}, true},
+		{KnockoutRule{Type: knockoutKeyword, Value: "  "}, false},
+		{KnockoutRule{Type: knockoutWorkPermit}, false},
+		{KnockoutRule{Type: knockoutMinDegree, Value: "some college"}, false},
+		{KnockoutRule{Type: knockoutMinYears, Value: "five"}, false},
+		{KnockoutRule{Type: knockoutMinYears, Value: "-1"}, false},
+		{KnockoutRule{Type: "clearance", Value: "SC"}, false},
+		{KnockoutRule{Type: knockoutKeyword, Value: "go", Check: "llm"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.rule.validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("validate(%+v) = %v, want ok %v", tt.rule, err, tt.ok)
+		}
+	}
+}
+
+func TestDegreeLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		ok    bool
+	}{
+		{"doctorate", 5, true},
+		{"PhD in CS", 5, true},
+		{"Masters", 4, true},
+		{"Master's degree", 4, true},
+		{"MBA", 4, true},
+		{"MS", 4, true},
+		{"Bachelors", 3, true},
+		{"BSc", 3, true},
+		{"associate", 2, true},
+		{"high school", 1, true},
+		{"some college", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		level, ok := degreeLevel(tt.name)
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("degreeLevel(%q) = %d, %v, want %d, %v", tt.name, level, ok, tt.level, tt.ok)
+		}
+	}
+}
+
+func TestHighestDegree(t *testing.T) {
+	tests := []struct {
+		text  string
+		level int
+		name  string
+	}{
+		{"B.Sc. Physics\nM.Sc. Data Science", 4, "master"},
+		{"PhD, Machine Learning, 2020", 5, "doctorate"},
+		{"Bachelor of Engineering", 3, "bachelor"},
+		{"HND Computer Science", 2, "associate"},
+		{"WAEC, 2010", 1, "high school"},
+		// a job title is not a degree
+		{"Scrum Master at Acme", 0, ""},
+		{"Self-taught developer", 0, ""},
+	}
+	for _, tt := range tests {
+		level, name := highestDegree(tt.text)
+		if level != tt.level || name != tt.name {
+			t.Errorf("highestDegree(%q) = %d, %q, want %d, %q", tt.text, level, name, tt.level, tt.name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var wordPattern = regexp.MustCompile(`\p{L}+`)
+
+// languageStopwords are the most frequent function words of the languages
+// we see resumes in, keyed by ISO 639-1 code.
+var languageStopwords = map[string]map[string]bool{}
+
+func init() {
+	for lang, words := range map[string]string{
+		"en": "the and of to in for with on at by from as is was are were have has be this that an or our",
+		"fr": "le la les des et du de un une pour dans avec sur par est sont au aux en ce qui que nous",
+		"de": "der die das und mit von zu den dem des ein eine für auf im ist sind bei als auch oder nicht",
+		"es": "el la los las y de del en con para por un una es son al como que se su sus",
+		"pt": "o a os as e de do da dos das em com para por um uma é são no na ao como",
+		"it": "il lo la gli le e di del della in con per un una è sono al nel che dei delle",
+		"nl": "de het een en van in met voor op te bij als is zijn aan naar door ook niet",
+	} {
+		set := map[string]bool{}
+		for _, w := range strings.Fields(words) {
+			set[w] = true
+		}
+		languageStopwords[lang] = set
+	}
+}
+
+// detectLanguage guesses the language of a text from its stopwords. It
+// returns "" when the text is too short or no language clearly wins.
+func detectLanguage(text string) string {
+	words := wordPattern.FindAllString(strings.ToLower(text), 2000)
+	if len(words) < 20 {
+		return ""
+	}
+	best, bestHits, runnerUp := "", 0, 0
+	for lang, set := range languageStopwords {
+		hits := 0
+		for _, word := range words {
+			if set[word] {
+				hits++
+			}
+		}
+		switch {
+		case hits > bestHits:
+			best, bestHits, runnerUp = lang, hits, bestHits
+		case hits > runnerUp:
+			runnerUp = hits
+		}
+	}
+	// shared words ("de", "la", ...) need a clear margin
+	if bestHits < 5 || float64(bestHits) < 1.2*float64(runnerUp) {
+		return ""
+	}
+	return best
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -14,9 +17,14 @@ import (
 	"github.com/streadway/amqp"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
+	"google.golang.org/genai"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == sandboxChildArg {
+		runSandboxChild(os.Args[2:])
+		return
+	}
 	_ = godotenv.Load()
 	dbUrl := os.Getenv("DB_URL")
 	if dbUrl == "" {
@@ -65,17 +73,94 @@ func main() {
 		log.Fatal("error creating aws config", err)
 	}
 
+	downloadLimits, err := loadDownloadLimits(
+		os.Getenv("DOWNLOAD_MAX_BYTES"),
+		os.Getenv("DOWNLOAD_SPOOL_BYTES"),
+		os.Getenv("DOWNLOAD_TEMP_DIR"),
+	)
+	if err != nil {
+		log.Fatalf("invalid download limits in environment: %v", err)
+	}
+	storage := NewBlobStores(downloadLimits)
+	storage.Register(storageR2, NewS3Store(awsConfig, fmt.Sprintf("https://%s.r2.cloudflarestorage.com", r2Config.AccountID), r2Config.Bucket, false, nil))
+	if s3Bucket := os.Getenv("S3_BUCKET"); s3Bucket != "" {
+		s3Store, err := loadS3Store(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_REGION"),
+			s3Bucket,
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_PATH_STYLE"),
+			os.Getenv("S3_ALLOWED_BUCKETS"),
+		)
+		if err != nil {
+			log.Fatalf("invalid S3 storage config in environment: %v", err)
+		}
+		storage.Register(storageS3, s3Store)
+	}
+	if localDir := os.Getenv("LOCAL_STORAGE_DIR"); localDir != "" {
+		localStore, err := NewLocalStore(localDir)
+		if err != nil {
+			log.Fatalf("invalid LOCAL_STORAGE_DIR in environment: %v", err)
+		}
+		storage.Register(storageLocal, localStore)
+	}
+	httpTimeout := 60 * time.Second
+	if v := os.Getenv("HTTP_STORAGE_TIMEOUT"); v != "" {
+		httpTimeout, err = time.ParseDuration(v)
+		if err != nil || httpTimeout <= 0 {
+			log.Fatalf("invalid HTTP_STORAGE_TIMEOUT %q in environment", v)
+		}
+	}
+	// arbitrary URLs are never fetched: the HTTP store needs an allowlist
+	if allowedHosts := os.Getenv("HTTP_STORAGE_ALLOWED_HOSTS"); strings.TrimSpace(allowedHosts) != "" {
+		storage.Register(storageHTTP, NewHTTPStore(httpTimeout, strings.Split(allowedHosts, ",")))
+	} else {
+		log.Println("HTTP storage disabled: set HTTP_STORAGE_ALLOWED_HOSTS to fetch resumes by URL")
+	}
+
+	reportStorage := os.Getenv("REPORT_STORAGE_PROVIDER")
+	switch reportStorage {
+	case "":
+		reportStorage = storageR2
+	case "none":
+		reportStorage = ""
+		log.Println("session reports disabled")
+	}
+	if reportStorage != "" {
+		store, err := storage.For(reportStorage)
+		if err != nil {
+			log.Fatalf("invalid REPORT_STORAGE_PROVIDER in environment: %v", err)
+		}
+		if _, ok := store.(BlobWriter); !ok {
+			log.Fatalf("invalid REPORT_STORAGE_PROVIDER in environment: %q can't store files", reportStorage)
+		}
+	}
+
 	googleApiKey := os.Getenv("GOOGLE_API_KEY")
 	if googleApiKey == "" {
 		log.Fatal("empty GOOGLE_API_KEY in env")
 	}
 	// create agent and runner
 	agentName := "resume analyzer"
-	analyzer, err := GetAgent(googleApiKey, agentName)
+	analyzer, err := GetAgent(googleApiKey, agentName, nil)
 	if err != nil {
 		log.Fatalf("failed to create agent: %v", err)
 	}
 
+	scoringMode := os.Getenv("SCORING_MODE")
+	if scoringMode == "" {
+		scoringMode = scoringMethodLLM
+	}
+	if scoringMode != scoringMethodLLM && scoringMode != scoringMethodLexical {
+		log.Fatalf("invalid SCORING_MODE %q in environment, expected llm or lexical", scoringMode)
+	}
+	lexicalFallback := os.Getenv("LEXICAL_FALLBACK") == "true"
+	scoreWeights, err := parseScoreWeights(os.Getenv("SCORE_WEIGHTS"))
+	if err != nil {
+		log.Fatalf("invalid SCORE_WEIGHTS in environment: %v", err)
+	}
+
 	//  create session for ai use
 	// Create a session to examine its properties.
 	inMemoryService := session.InMemoryService()
@@ -92,6 +177,129 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create runner: %v", err)
 	}
+	consistency, err := loadConsistencyConfig(
+		os.Getenv("CONSISTENCY_SAMPLES"),
+		os.Getenv("CONSISTENCY_MODE"),
+		os.Getenv("CONSISTENCY_TEMPERATURE"),
+		os.Getenv("CONSISTENCY_MAX_STDDEV"),
+	)
+	if err != nil {
+		log.Fatalf("invalid consistency config in environment: %v", err)
+	}
+	// a second, warmer agent for self-consistency sampling, built even when
+	// sampling is off by default since sessions can opt in
+	samplingAgentName := "resume analyzer sampler"
+	var samplingRunner *runner.Runner
+	if consistency.Mode == consistencyModeTemperature {
+		sampler, err := GetAgent(googleApiKey, samplingAgentName, &genai.GenerateContentConfig{
+			Temperature: genai.Ptr(consistency.Temperature),
+		})
+		if err != nil {
+			log.Fatalf("failed to create sampling agent: %v", err)
+		}
+		samplingRunner, err = runner.New(runner.Config{
+			AppName:        sampler.Name(),
+			Agent:          sampler,
+			SessionService: inMemoryService,
+		})
+		if err != nil {
+			log.Fatalf("failed to create sampling runner: %v", err)
+		}
+	}
+
+	employmentGapMonths := 6
+	if v := os.Getenv("EMPLOYMENT_GAP_MONTHS"); v != "" {
+		employmentGapMonths, err = strconv.Atoi(v)
+		if err != nil || employmentGapMonths < 0 {
+			log.Fatalf("invalid EMPLOYMENT_GAP_MONTHS %q in environment", v)
+		}
+	}
+
+	knockoutModel := os.Getenv("KNOCKOUT_MODEL")
+	if knockoutModel == "" {
+		knockoutModel = "gemini-2.5-flash"
+	}
+	knockoutAgentName := "resume screener"
+	screener, err := GetKnockoutAgent(googleApiKey, knockoutModel, knockoutAgentName)
+	if err != nil {
+		log.Fatalf("failed to create knockout agent: %v", err)
+	}
+	knockoutRunner, err := runner.New(runner.Config{
+		AppName:        screener.Name(),
+		Agent:          screener,
+		SessionService: inMemoryService,
+	})
+	if err != nil {
+		log.Fatalf("failed to create knockout runner: %v", err)
+	}
+
+	extractors := NewExtractorRegistry(builtinExtractors()...)
+	overrides, err := parseExtractorMap(os.Getenv("EXTRACTOR_OVERRIDES"))
+	if err != nil {
+		log.Fatalf("invalid EXTRACTOR_OVERRIDES in environment: %v", err)
+	}
+	for mime, name := range overrides {
+		if err := extractors.Override(mime, name); err != nil {
+			log.Fatalf("invalid EXTRACTOR_OVERRIDES in environment: %v", err)
+		}
+	}
+	shadows, err := parseExtractorMap(os.Getenv("EXTRACTOR_SHADOW"))
+	if err != nil {
+		log.Fatalf("invalid EXTRACTOR_SHADOW in environment: %v", err)
+	}
+	for mime, name := range shadows {
+		if err := extractors.Shadow(mime, name); err != nil {
+			log.Fatalf("invalid EXTRACTOR_SHADOW in environment: %v", err)
+		}
+	}
+
+	ocr, err := loadOCREngine(
+		os.Getenv("OCR_ENABLED"),
+		os.Getenv("TESSERACT_PATH"),
+		os.Getenv("PDFTOPPM_PATH"),
+		os.Getenv("OCR_LANGUAGES"),
+		os.Getenv("OCR_DPI"),
+		os.Getenv("OCR_MAX_PAGES"),
+	)
+	if err != nil {
+		log.Fatalf("invalid OCR config in environment: %v", err)
+	}
+	if ocr != nil {
+		extractors.EnableOCR(ocr)
+	} else {
+		log.Println("OCR disabled: scanned resumes and images won't be read")
+	}
+
+	sandbox, err := loadSandbox(
+		os.Getenv("EXTRACT_SANDBOX"),
+		os.Getenv("EXTRACT_TIMEOUT"),
+		os.Getenv("EXTRACT_MEMORY_MB"),
+	)
+	if err != nil {
+		log.Fatalf("invalid sandbox config in environment: %v", err)
+	}
+	extractors.SetSandbox(sandbox)
+	if sandbox.Path == "" {
+		log.Println("sandbox disabled: files are parsed in the worker process")
+	}
+
+	scanner, err := loadScanner(os.Getenv("CLAMD_ADDRESS"), os.Getenv("CLAMD_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("invalid malware scanner config in environment: %v", err)
+	}
+	if _, ok := scanner.(noopScanner); ok {
+		log.Println("malware scanning disabled: set CLAMD_ADDRESS to scan uploads")
+	}
+
+	archiveLimits, err := loadArchiveLimits(
+		os.Getenv("ARCHIVE_MAX_FILES"),
+		os.Getenv("ARCHIVE_MAX_BYTES"),
+		os.Getenv("ARCHIVE_MAX_DEPTH"),
+	)
+	if err != nil {
+		log.Fatalf("invalid archive limits in environment: %v", err)
+	}
+
 	conn, err := amqp.Dial(rabbitmqUrl)
 	if err != nil {
 		log.Fatalf("error connecting to RabbitMQ. err:  %v", err)
@@ -104,10 +312,27 @@ func main() {
 		AgentSessionService: inMemoryService,
 		DB:                  dbqueries,
 		// GoogleApiKey:        googleApiKey,
-		R2:          &r2Config,
-		AwsConfig:   &awsConfig,
-		RABBITMQUrl: rabbitmqUrl,
-		RabbitConn:  conn,
+		Storage: storage,
+		Scanner: scanner,
+
+		ReportStorage: reportStorage,
+		RABBITMQUrl:   rabbitmqUrl,
+		RabbitConn:    conn,
+
+		ScoringMode:     scoringMode,
+		LexicalFallback: lexicalFallback,
+		ScoreWeights:    scoreWeights,
+
+		Consistency:       consistency,
+		SamplingRunner:    samplingRunner,
+		SamplingAgentName: samplingAgentName,
+		KnockoutRunner:    knockoutRunner,
+		KnockoutAgentName: knockoutAgentName,
+
+		EmploymentGapMonths: employmentGapMonths,
+		Extractors:          extractors,
+		Sandbox:             sandbox,
+		Archive:             archiveLimits,
 	}
 
 	fmt.Println("Starting 3 workers consumer pool ")
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+const mdEscapeBase = 0xE000
+
+var (
+	mdHeading      = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdSetextRule   = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdRule         = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	mdFence        = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	mdBullet       = regexp.MustCompile(`^(\s*)[-*+]\s+(\[[ xX]\]\s+)?`)
+	mdOrdered      = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+`)
+	mdQuote        = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	mdTableDivider = regexp.MustCompile(`^\s*\|?\s*:?-{2,}:?\s*(\|\s*:?-{2,}:?\s*)*\|?\s*$`)
+	mdRefDef       = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]+)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	mdRefLink      = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutoLink     = regexp.MustCompile(`<((?:https?://|mailto:)[^>\s]+)>`)
+	mdStrong       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmphasis     = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_]*?\S)?)[*_]($|[^\w*])`)
+	mdStrike       = regexp.MustCompile(`~~(.+?)~~`)
+	mdCode         = regexp.MustCompile("`+([^`]+)`+")
+	mdHTMLTag      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdEscape       = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|>~])`)
+)
+
+// extractMarkdownText turns a Markdown resume into plain text: heading
+// markers, emphasis and inline HTML are dropped, links keep their target in
+// brackets, list items get a "- " prefix and tables become "cell | cell"
+// lines. Code blocks are kept verbatim and YAML front matter is removed.
+func extractMarkdownText(data []byte) (string, error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, utf8BOM)), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	// front matter
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if t := strings.TrimSpace(lines[i]); t == "---" || t == "..." {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var out []string
+	inFence := ""
+	for i, line := range lines {
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			switch inFence {
+			case "":
+				inFence = m[1]
+			case m[1]:
+				inFence = ""
+			}
+			continue
+		}
+		if inFence != "" {
+			out = append(out, line)
+			continue
+		}
+
+		switch {
+		case mdSetextRule.MatchString(line) && i > 0 && strings.TrimSpace(lines[i-1]) != "" && len(out) > 0:
+			// underline of the heading on the previous line
+			out = append(out, "")
+			continue
+		case mdRule.MatchString(line), mdTableDivider.MatchString(line) && strings.Contains(line, "|"), mdRefDef.MatchString(line):
+			continue
+		}
+
+		line = mdQuote.ReplaceAllString(line, "")
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			if n := len(out); n > 0 && out[n-1] != "" {
+				out = append(out, "")
+			}
+			out = append(out, renderMarkdownInline(m[1]))
+			continue
+		}
+
+		prefix := ""
+		if m := mdBullet.FindStringSubmatch(line); m != nil {
+			prefix = m[1] + "- "
+			line = line[len(m[0]):]
+		} else if m := mdOrdered.FindStringSubmatch(line); m != nil {
+			prefix = m[1] + m[2] + ". "
+			line = line[len(m[0]):]
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "|") || strings.HasSuffix(trimmed, "|") && strings.Count(trimmed, "|") > 1 {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			line = strings.Join(nonEmpty(cells), " | ")
+		}
+		out = append(out, strings.TrimRight(prefix+renderMarkdownInline(line), " "))
+	}
+
+	result := strings.TrimSpace(strings.Join(out, "\n"))
+	return blankLinesPattern.ReplaceAllString(result, "\n\n"), nil
+}
+
+// renderMarkdownInline strips inline Markdown markup from one line.
+func renderMarkdownInline(s string) string {
+	// hide escaped characters from the patterns below by moving them to
+	// the private use area
+	s = mdEscape.ReplaceAllStringFunc(s, func(m string) string {
+		return string(rune(mdEscapeBase + int(m[1])))
+	})
+	s = mdCode.ReplaceAllString(s, "$1")
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := mdLink.FindStringSubmatch(m)
+		label, target := parts[1], strings.TrimPrefix(parts[2], "mailto:")
+		if strings.Contains(label, target) || strings.HasPrefix(target, "#") {
+			return label
+		}
+		return label + " (" + target + ")"
+	})
+	s = mdRefLink.ReplaceAllString(s, "$1")
+	s = mdAutoLink.ReplaceAllStringFunc(s, func(m string) string {
+		return strings.TrimPrefix(strings.Trim(m, "<>"), "mailto:")
+	})
+	s = mdHTMLTag.ReplaceAllString(s, "")
+	s = mdStrong.ReplaceAllString(s, "$2")
+	s = mdEmphasis.ReplaceAllString(s, "$1$2$3")
+	s = mdStrike.ReplaceAllString(s, "$1")
+	s = strings.Map(func(r rune) rune {
+		if r >= mdEscapeBase && r < mdEscapeBase+0x80 {
+			return r - mdEscapeBase
+		}
+		return r
+	}, s)
+	// a trailing backslash or two spaces are hard line breaks
+	return strings.TrimRight(strings.TrimSuffix(s, "\\"), " ")
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/google/uuid"
 	"github.com/muhammadolammi/jobmatchworker/internal/database"
 	"github.com/streadway/amqp"
@@ -21,13 +20,42 @@ type R2Config struct {
 type WorkerConfig struct {
 	DB *database.Queries
 	// GoogleApiKey        string
-	R2                  *R2Config
-	AwsConfig           *aws.Config
+	// Storage fetches resume files from the provider each resume names.
+	Storage *BlobStores
+	// ReportStorage is the provider session reports are uploaded to; empty
+	// disables them. Exports go next to the session's resumes, and here
+	// only when the resumes' store is read-only.
+	ReportStorage string
+	// Scanner checks downloads for malware before they're parsed.
+	Scanner             Scanner
 	RabbitConn          *amqp.Connection
 	RABBITMQUrl         string
 	AgentRunner         *runner.Runner
 	AgentSessionService session.Service
 	AgentName           string
+	// ScoringMode is "llm" (default) or "lexical" to rank without the agent.
+	ScoringMode string
+	// LexicalFallback scores with the lexical scorer when the agent fails.
+	LexicalFallback bool
+	// ScoreWeights blends the agent score with deterministic signals.
+	ScoreWeights ScoreWeights
+	// Consistency enables multi-sample scoring. SamplingRunner is the
+	// higher-temperature agent used by the temperature mode.
+	Consistency       ConsistencyConfig
+	SamplingRunner    *runner.Runner
+	SamplingAgentName string
+	// KnockoutRunner checks model-evaluated knockout rules.
+	KnockoutRunner    *runner.Runner
+	KnockoutAgentName string
+	// EmploymentGapMonths is the gap length above which employment gaps
+	// are flagged.
+	EmploymentGapMonths int
+	// Sandbox isolates parsing of untrusted files from the worker.
+	Sandbox *Sandbox
+	// Extractors turns downloaded files into text.
+	Extractors *ExtractorRegistry
+	// Archive limits the expansion of ZIP uploads.
+	Archive ArchiveLimits
 }
 
 type AnalysesResult struct {
@@ -38,9 +66,36 @@ type AnalysesResult struct {
 	MissingSkills       []string `json:"missing_skills"`
 	Summary             string   `json:"summary"`
 	Recomendation       string   `json:"recommendation"`
+	// Method is how the score was produced: "llm" or "lexical".
+	Method         string   `json:"method"`
+	MatchedTerms   []string `json:"matched_terms,omitempty"`
+	MissingTerms   []string `json:"missing_terms,omitempty"`
+	FallbackReason string   `json:"fallback_reason,omitempty"`
+	// ScoreBreakdown explains how MatchScore was derived for llm results.
+	ScoreBreakdown *ScoreBreakdown    `json:"score_breakdown,omitempty"`
+	Consistency    *Consistency       `json:"consistency,omitempty"`
+	Experience     *ExperienceSummary `json:"experience,omitempty"`
+	// Filename is the uploaded file's name. For files inside an uploaded
+	// ZIP, Archive is the archive's name and Filename the path within it
+	Archive  string `json:"archive,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	// set for resumes received by email
+	Email *EmailSummary `json:"email,omitempty"`
+	// which extractor produced the text, and what it noticed
+	Extraction *Extraction `json:"extraction,omitempty"`
+	// set when the file's content didn't match its declared MIME type
+	DeclaredMime string `json:"declared_mime,omitempty"`
+	DetectedMime string `json:"detected_mime,omitempty"`
+	// Knockout result entry
+	KnockedOut     bool          `json:"knocked_out,omitempty"`
+	FailedRule     *KnockoutRule `json:"failed_rule,omitempty"`
+	KnockoutReason string        `json:"knockout_reason,omitempty"`
 	// Error result entry
 	IsErrorResult bool   `json:"is_error_result"`
 	Error         string `json:"error,omitempty"`
+	// ErrorCode classifies errors clients handle specially, e.g.
+	// "unreadable_document".
+	ErrorCode string `json:"error_code,omitempty"`
 }
 type AnalysesResults struct {
 	ID        uuid.UUID        `json:"id"`
@@ -58,4 +113,25 @@ type Session struct {
 	Status         string    `json:"status"`
 	JobTitle       string    `json:"job_title"`
 	JobDescription string    `json:"job_description"`
+	// KnockoutRules are checked before the full analysis.
+	KnockoutRules []KnockoutRule `json:"knockout_rules,omitempty"`
+	// ConsistencySamples opts the session in or out of multi-sample scoring;
+	// 0 uses the worker's default.
+	ConsistencySamples int `json:"consistency_samples,omitempty"`
+}
+
+// SessionMessage asks for a session to be analysed. Only the ID is trusted;
+// the session itself is loaded from the database. Other fields are metadata
+// for the publisher and are ignored.
+type SessionMessage struct {
+	SessionID uuid.UUID `json:"session_id"`
+	// ID is set instead by publishers that still send the whole session.
+	ID uuid.UUID `json:"id"`
+}
+
+func (m SessionMessage) sessionID() uuid.UUID {
+	if m.SessionID != uuid.Nil {
+		return m.SessionID
+	}
+	return m.ID
 }
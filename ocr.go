@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// below this many letters per page an extraction is treated as image
+	// only
+	ocrMinLettersPerPage = 50
+	ocrPageTimeout       = 90 * time.Second
+)
+
+// OCREngine runs a locally installed tesseract, with pdftoppm rendering PDF
+// pages to images first.
+type OCREngine struct {
+	Tesseract string
+	Pdftoppm  string
+	// Languages is tesseract's -l argument, e.g. "eng+fra".
+	Languages string
+	DPI       int
+	// MaxPages bounds the work spent on long scanned PDFs.
+	MaxPages int
+}
+
+// loadOCREngine finds the OCR binaries. It returns nil when OCR is
+// disabled or tesseract isn't installed.
+func loadOCREngine(enabled, tesseract, pdftoppm, languages, dpi, maxPages string) (*OCREngine, error) {
+	if enabled == "false" {
+		return nil, nil
+	}
+	engine := &OCREngine{Languages: "eng", DPI: 300, MaxPages: 10}
+	if tesseract == "" {
+		tesseract = "tesseract"
+	}
+	path, err := exec.LookPath(tesseract)
+	if err != nil {
+		if enabled == "true" {
+			return nil, fmt.Errorf("tesseract not found: %w", err)
+		}
+		return nil, nil
+	}
+	engine.Tesseract = path
+	if pdftoppm == "" {
+		pdftoppm = "pdftoppm"
+	}
+	// without pdftoppm only image uploads can be OCRed
+	if path, err := exec.LookPath(pdftoppm); err == nil {
+		engine.Pdftoppm = path
+	}
+	if languages != "" {
+		engine.Languages = languages
+	}
+	if dpi != "" {
+		n, err := strconv.Atoi(dpi)
+		if err != nil || n < 72 || n > 600 {
+			return nil, fmt.Errorf("invalid dpi %q, expected 72-600", dpi)
+		}
+		engine.DPI = n
+	}
+	if maxPages != "" {
+		n, err := strconv.Atoi(maxPages)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid max pages %q", maxPages)
+		}
+		engine.MaxPages = n
+	}
+	return engine, nil
+}
+
+// needsOCR reports whether an extraction came back (nearly) empty, as
+// happens for scanned and image-only PDFs.
+func needsOCR(extraction Extraction) bool {
+	letters := 0
+	for _, r := range extraction.Text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters < ocrMinLettersPerPage*max(extraction.PageCount, 1)
+}
+
+// Image OCRs a single PNG, JPEG or TIFF image.
+func (o *OCREngine) Image(data []byte) (Extraction, error) {
+	dir, err := os.MkdirTemp("", "resume-ocr-")
+	if err != nil {
+		return Extraction{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "page")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return Extraction{}, err
+	}
+	text, confidence, err := o.recognize(path)
+	if err != nil {
+		return Extraction{}, err
+	}
+	return Extraction{Text: text, PageCount: 1, OCR: true, OCRConfidence: math.Round(confidence*100) / 100}, nil
+}
+
+// PDF renders a PDF's pages to images and OCRs them.
+func (o *OCREngine) PDF(data []byte) (Extraction, error) {
+	if o.Pdftoppm == "" {
+		return Extraction{}, fmt.Errorf("pdftoppm is not installed")
+	}
+	dir, err := os.MkdirTemp("", "resume-ocr-")
+	if err != nil {
+		return Extraction{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "resume.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return Extraction{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ocrPageTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, o.Pdftoppm,
+		"-r", strconv.Itoa(o.DPI), "-gray", "-png",
+		"-f", "1", "-l", strconv.Itoa(o.MaxPages),
+		input, filepath.Join(dir, "page"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return Extraction{}, fmt.Errorf("pdftoppm failed: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil || len(pages) == 0 {
+		return Extraction{}, fmt.Errorf("pdftoppm produced no pages")
+	}
+	// page-1.png, page-2.png, ... page-10.png; zero padding depends on the
+	// page count, so sort by number
+	sort.Slice(pages, func(i, j int) bool { return ocrPageNumber(pages[i]) < ocrPageNumber(pages[j]) })
+
+	var texts []string
+	var warnings []string
+	var confidenceSum float64
+	var recognized int
+	for i, page := range pages {
+		text, confidence, err := o.recognize(page)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("OCR failed on page %d: %v", i+1, err))
+			continue
+		}
+		texts = append(texts, text)
+		if text != "" {
+			confidenceSum += confidence
+			recognized++
+		}
+	}
+	if len(texts) == 0 {
+		return Extraction{}, fmt.Errorf("OCR failed on every page")
+	}
+	extraction := Extraction{
+		Text:     strings.TrimSpace(strings.Join(texts, pageBreak)),
+		OCR:      true,
+		Warnings: warnings,
+	}
+	if recognized > 0 {
+		extraction.OCRConfidence = math.Round(confidenceSum/float64(recognized)*100) / 100
+	}
+	return extraction, nil
+}
+
+func ocrPageNumber(path string) int {
+	base := strings.TrimSuffix(filepath.Base(path), ".png")
+	n, _ := strconv.Atoi(base[strings.LastIndexByte(base, '-')+1:])
+	return n
+}
+
+// recognize runs tesseract on one image and rebuilds the text from its TSV
+// output, which also carries per-word confidences. The returned confidence
+// is the mean word confidence scaled to 0-1.
+func (o *OCREngine) recognize(image string) (string, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocrPageTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.Tesseract, image, "stdout", "-l", o.Languages, "--psm", "3", "tsv")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("tesseract failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return parseTesseractTSV(out)
+}
+
+// parseTesseractTSV joins recognised words into lines, with a blank line
+// between paragraphs.
+func parseTesseractTSV(tsv []byte) (string, float64, error) {
+	var b strings.Builder
+	var confidenceSum float64
+	var words int
+	prevPar, prevLine := "", ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(tsv))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		// level page block par line word left top width height conf text
+		fields := strings.SplitN(scanner.Text(), "\t", 12)
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+		word := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if word == "" || err != nil || conf < 0 {
+			continue
+		}
+		par := fields[1] + "/" + fields[2] + "/" + fields[3]
+		line := par + "/" + fields[4]
+		switch {
+		case b.Len() == 0:
+		case par != prevPar:
+			b.WriteString("\n\n")
+		case line != prevLine:
+			b.WriteString("\n")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(word)
+		prevPar, prevLine = par, line
+		confidenceSum += conf
+		words++
+	}
+	if err := scanner.Err(); err != nil {
+		return "", 0, err
+	}
+	if words == 0 {
+		return "", 0, nil
+	}
+	return b.String(), confidenceSum / float64(words) / 100, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const tsvHeader = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+
+// tsvWord builds a word row (level 5) of tesseract's TSV output.
+func tsvWord(block, par, line int, conf, text string) string {
+	return strings.Join([]string{"5", "1", strconv.Itoa(block), strconv.Itoa(par), strconv.Itoa(line), "1", "0", "0", "10", "10", conf, text}, "\t") + "\n"
+}
+
+func TestParseTesseractTSV(t *testing.T) {
+	tests := []struct {
+		name       string
+		tsv        string
+		text       string
+		confidence float64
+	}{
+		{
+			name: "lines and paragraphs",
+			tsv: tsvHeader +
+				"1\t1\t0\t0\t0\t0\t0\t0\t100\t100\t-1\t\n" +
+				tsvWord(1, 1, 1, "96", "Jane") +
+				tsvWord(1, 1, 1, "90", "Doe") +
+				tsvWord(1, 1, 2, "88", "Engineer") +
+				tsvWord(2, 1, 1, "70", "Skills"),
+			text:       "Jane Doe\nEngineer\n\nSkills",
+			confidence: 0.86,
+		},
+		{
+			name: "blank words and negative confidence skipped",
+			tsv: tsvHeader +
+				tsvWord(1, 1, 1, "80", "Go") +
+				tsvWord(1, 1, 1, "-1", "ghost") +
+				tsvWord(1, 1, 1, "95", " ") +
+				tsvWord(1, 1, 1, "60", "Rust"),
+			text:       "Go Rust",
+			confidence: 0.7,
+		},
+		{
+			name:       "no words",
+			tsv:        tsvHeader + "1\t1\t0\t0\t0\t0\t0\t0\t100\t100\t-1\t\n",
+			text:       "",
+			confidence: 0,
+		},
+		{
+			name:       "short rows ignored",
+			tsv:        tsvHeader + "5\t1\t1\n" + tsvWord(1, 1, 1, "50", "ok"),
+			text:       "ok",
+			confidence: 0.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, confidence, err := parseTesseractTSV([]byte(tt.tsv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if diff := confidence - tt.confidence; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("confidence = %v, want %v", confidence, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestNeedsOCR(t *testing.T) {
+	page := strings.Repeat("resume ", 10) // 60 letters
+	tests := []struct {
+		name       string
+		extraction Extraction
+		want       bool
+	}{
+		{"empty text layer", Extraction{Text: "", PageCount: 1}, true},
+		{"text layer", Extraction{Text: page, PageCount: 1}, false},
+		{"one page of text over three pages", Extraction{Text: page, PageCount: 3}, true},
+		{"digits and punctuation only", Extraction{Text: strings.Repeat("12/34 - ", 20), PageCount: 1}, true},
+		// formats without pages count as one
+		{"no page count", Extraction{Text: page}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsOCR(tt.extraction); got != tt.want {
+				t.Errorf("needsOCR() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	odfTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odfTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odfStyleNS  = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+	odfOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+)
+
+var xmlSpacePattern = regexp.MustCompile(`[ \t\r\n]+`)
+
+// extractODTText returns the text of an OpenDocument text file: page
+// headers first, then the body. Paragraphs and headings become lines, list
+// items get a "- " prefix, table rows become "cell | cell" lines and link
+// targets follow their link text.
+func extractODTText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse odt: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files["content.xml"] == nil {
+		return "", fmt.Errorf("failed to parse odt: missing content.xml")
+	}
+
+	var sections []string
+	if f := files["styles.xml"]; f != nil {
+		// headers and footers live in the master page styles
+		headers, err := readODFPart(f, true)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse styles.xml: %w", err)
+		}
+		if headers = strings.TrimSpace(headers); headers != "" {
+			sections = append(sections, headers)
+		}
+	}
+	body, err := readODFPart(files["content.xml"], false)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse content.xml: %w", err)
+	}
+	sections = append(sections, strings.TrimSpace(body))
+
+	return blankLinesPattern.ReplaceAllString(strings.TrimSpace(strings.Join(sections, "\n\n")), "\n\n"), nil
+}
+
+type odfParagraph struct {
+	text  strings.Builder
+	links []string
+	list  bool
+}
+
+type odfWalker struct {
+	lines      []string
+	paragraphs []*odfParagraph
+	// paragraphs of each open table cell
+	cells [][]string
+	// cells of each open table row
+	rows [][]string
+	// a list item was opened and its first paragraph hasn't started yet
+	pendingItem bool
+}
+
+func (w *odfWalker) paragraph() *odfParagraph {
+	if len(w.paragraphs) == 0 {
+		return nil
+	}
+	return w.paragraphs[len(w.paragraphs)-1]
+}
+
+func (w *odfWalker) write(s string) {
+	if p := w.paragraph(); p != nil {
+		p.text.WriteString(s)
+	}
+}
+
+func (w *odfWalker) emit(line string) {
+	if n := len(w.cells); n > 0 {
+		w.cells[n-1] = append(w.cells[n-1], line)
+		return
+	}
+	w.lines = append(w.lines, line)
+}
+
+// readODFPart walks an ODF XML part. With headersOnly, only the page
+// header and footer content of styles.xml is read.
+func readODFPart(f *zip.File, headersOnly bool) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	w := &odfWalker{}
+	// depth inside style:header/footer elements
+	inHeader := 0
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if isODFHeader(el.Name) {
+				inHeader++
+				continue
+			}
+			if headersOnly && inHeader == 0 {
+				continue
+			}
+			switch el.Name.Space {
+			case odfTextNS:
+				switch el.Name.Local {
+				case "p", "h":
+					w.paragraphs = append(w.paragraphs, &odfParagraph{list: w.pendingItem})
+					w.pendingItem = false
+				case "list-item":
+					w.pendingItem = true
+				case "s":
+					n := 1
+					if c, err := strconv.Atoi(xmlAttr(el, "c")); err == nil && c > 0 {
+						n = min(c, 64)
+					}
+					w.write(strings.Repeat(" ", n))
+				case "tab":
+					w.write("\t")
+				case "line-break":
+					w.write("\n")
+				case "a":
+					if p := w.paragraph(); p != nil {
+						if href := xmlAttr(el, "href"); href != "" {
+							p.links = append(p.links, href)
+						}
+					}
+				case "note-citation", "tracked-changes", "sequence-decls", "variable-decls", "user-field-decls":
+					// footnote numbers, deleted text and declarations
+					if err := dec.Skip(); err != nil {
+						return "", err
+					}
+				}
+			case odfTableNS:
+				switch el.Name.Local {
+				case "table-row":
+					w.rows = append(w.rows, nil)
+				case "table-cell", "covered-table-cell":
+					w.cells = append(w.cells, nil)
+				}
+			case odfOfficeNS:
+				if el.Name.Local == "annotation" {
+					// reviewer comments
+					if err := dec.Skip(); err != nil {
+						return "", err
+					}
+				}
+			}
+
+		case xml.CharData:
+			if headersOnly && inHeader == 0 {
+				continue
+			}
+			// ODF collapses white space in text content; real spaces, tabs
+			// and breaks are elements
+			w.write(xmlSpacePattern.ReplaceAllString(string(el), " "))
+
+		case xml.EndElement:
+			if isODFHeader(el.Name) {
+				inHeader = max(inHeader-1, 0)
+				continue
+			}
+			if headersOnly && inHeader == 0 {
+				continue
+			}
+			switch el.Name.Space {
+			case odfTextNS:
+				switch el.Name.Local {
+				case "p", "h":
+					p := w.paragraph()
+					if p == nil {
+						continue
+					}
+					w.paragraphs = w.paragraphs[:len(w.paragraphs)-1]
+					line := strings.TrimSpace(p.text.String())
+					for _, link := range p.links {
+						display := strings.TrimPrefix(link, "mailto:")
+						if !strings.Contains(line, display) {
+							line += " (" + display + ")"
+						}
+					}
+					if p.list && line != "" {
+						line = "- " + line
+					}
+					w.emit(line)
+				case "list-item":
+					w.pendingItem = false
+				}
+			case odfTableNS:
+				switch el.Name.Local {
+				case "table-cell", "covered-table-cell":
+					if n := len(w.cells); n > 0 {
+						cell := strings.Join(nonEmpty(w.cells[n-1]), " ")
+						w.cells = w.cells[:n-1]
+						if r := len(w.rows); r > 0 {
+							w.rows[r-1] = append(w.rows[r-1], cell)
+						}
+					}
+				case "table-row":
+					if n := len(w.rows); n > 0 {
+						row := strings.Join(nonEmpty(w.rows[n-1]), " | ")
+						w.rows = w.rows[:n-1]
+						if row != "" {
+							w.emit(row)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return strings.Join(w.lines, "\n"), nil
+}
+
+// isODFHeader reports whether name is a master page header or footer.
+func isODFHeader(name xml.Name) bool {
+	if name.Space != odfStyleNS {
+		return false
+	}
+	switch name.Local {
+	case "header", "header-left", "header-first", "footer", "footer-left", "footer-first":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// minimum width of an empty vertical band for it to count as a gutter
+	// between columns, in points
+	minGutterWidth = 12.0
+	// glyph share allowed inside a gutter, so a stray overlapping glyph
+	// doesn't hide it
+	gutterNoise = 0.01
+	pageBreak   = "\n\f\n"
+	// maxPageWidth is the largest page the PDF spec allows (200 inches),
+	// used for pages without a usable MediaBox
+	maxPageWidth = 14400.0
+)
+
+// pdfLine is a run of glyphs sharing a baseline.
+type pdfLine struct {
+	y        float64
+	fontSize float64
+	glyphs   []pdf.Text
+}
+
+// extractPDFText lays out each page's glyphs by position: glyphs are grouped
+// into lines, two-column pages are read column by column, paragraph breaks
+// are restored from vertical gaps, words hyphenated across lines are joined
+// and pages are separated by form feeds. Link annotation targets (LinkedIn,
+// GitHub, ...) that don't appear in the text are appended at the end.
+func extractPDFText(reader io.ReaderAt) (Extraction, error) {
+	pdfReader, err := pdf.NewReader(reader, int64(lenReader(reader)))
+	if err != nil {
+		return Extraction{}, fmt.Errorf("failed to read pdf: %w", err)
+	}
+
+	var pages []string
+	var links, warnings []string
+	numPages := pdfReader.NumPage()
+	for i := 1; i <= numPages; i++ {
+		page := pdfReader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text := layoutPage(page.Content().Text, pageWidth(page))
+		if text == "" {
+			warnings = append(warnings, fmt.Sprintf("page %d has no extractable text", i))
+		}
+		pages = append(pages, text)
+		links = append(links, pageLinks(page)...)
+	}
+
+	text := strings.TrimSpace(strings.Join(pages, pageBreak))
+	var missing []string
+	seen := map[string]bool{}
+	for _, link := range links {
+		display := strings.TrimPrefix(link, "mailto:")
+		if seen[display] || strings.Contains(text, display) {
+			continue
+		}
+		seen[display] = true
+		missing = append(missing, display)
+	}
+	if len(missing) > 0 {
+		text += "\n\nLinks:\n" + strings.Join(missing, "\n")
+	}
+	return Extraction{Text: text, PageCount: numPages, Warnings: warnings}, nil
+}
+
+// extractPDFPlainText concatenates each page's plain text as the PDF library
+// returns it, without any layout analysis.
+func extractPDFPlainText(reader io.ReaderAt) (Extraction, error) {
+	pdfReader, err := pdf.NewReader(reader, int64(lenReader(reader)))
+	if err != nil {
+		return Extraction{}, fmt.Errorf("failed to read pdf: %w", err)
+	}
+	var b strings.Builder
+	numPages := pdfReader.NumPage()
+	for i := 1; i <= numPages; i++ {
+		page := pdfReader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, _ := page.GetPlainText(nil)
+		b.WriteString(text)
+	}
+	return Extraction{Text: b.String(), PageCount: numPages}, nil
+}
+
+// pageLinks returns the URI targets of the page's link annotations.
+func pageLinks(page pdf.Page) []string {
+	annots := page.V.Key("Annots")
+	var links []string
+	for i := 0; i < annots.Len(); i++ {
+		annot := annots.Index(i)
+		if annot.Key("Subtype").Name() != "Link" {
+			continue
+		}
+		if uri := strings.TrimSpace(annot.Key("A").Key("URI").RawString()); uri != "" {
+			links = append(links, uri)
+		}
+	}
+	return links
+}
+
+// pageWidth returns the width of the page's MediaBox, inherited from its
+// parents if need be, or maxPageWidth when it's missing or out of range.
+func pageWidth(page pdf.Page) float64 {
+	v := page.V
+	// a malformed Parent chain can loop
+	for depth := 0; depth < 32 && !v.IsNull(); depth++ {
+		if box := v.Key("MediaBox"); box.Len() == 4 {
+			width := math.Abs(box.Index(2).Float64() - box.Index(0).Float64())
+			if width > 0 && width <= maxPageWidth {
+				return width
+			}
+			break
+		}
+		v = v.Key("Parent")
+	}
+	return maxPageWidth
+}
+
+func layoutPage(glyphs []pdf.Text, pageWidth float64) string {
+	glyphs = dedupeGlyphs(glyphs)
+	if len(glyphs) == 0 {
+		return ""
+	}
+	lines := groupLines(glyphs)
+	gutterStart, gutterEnd, hasGutter := findGutter(glyphs, pageWidth)
+
+	var out []string
+	if !hasGutter {
+		return joinLines(lines)
+	}
+
+	// lines crossing the gutter (e.g. a centred name) are read in place; the
+	// lines between them are read left column then right column
+	var left, right []pdfLine
+	flush := func() {
+		if len(left) > 0 {
+			out = append(out, joinLines(left))
+		}
+		if len(right) > 0 {
+			out = append(out, joinLines(right))
+		}
+		left, right = nil, nil
+	}
+	mid := (gutterStart + gutterEnd) / 2
+	// lines above the first one with text in both columns are a full-width
+	// header, whichever side of the gutter they happen to sit on
+	header := true
+	for _, line := range lines {
+		l, r, spans := splitAtGutter(line.glyphs, mid)
+		if len(l) > 0 && len(r) > 0 {
+			header = false
+		}
+		if spans || header {
+			flush()
+			out = append(out, joinLines([]pdfLine{line}))
+			continue
+		}
+		if len(l) > 0 {
+			left = append(left, pdfLine{y: line.y, fontSize: line.fontSize, glyphs: l})
+		}
+		if len(r) > 0 {
+			right = append(right, pdfLine{y: line.y, fontSize: line.fontSize, glyphs: r})
+		}
+	}
+	flush()
+	return strings.Join(out, "\n\n")
+}
+
+// splitAtGutter splits a line's glyphs (sorted by X) into the parts left
+// and right of the gutter centre. A line whose text runs across the centre
+// without a word-sized break there spans both columns.
+func splitAtGutter(glyphs []pdf.Text, mid float64) (left, right []pdf.Text, spans bool) {
+	var prev *pdf.Text
+	for i := range glyphs {
+		g := &glyphs[i]
+		if strings.TrimSpace(g.S) == "" {
+			continue
+		}
+		if prev != nil && prev.X+prev.W/2 < mid && g.X+g.W/2 >= mid && g.X-(prev.X+prev.W) < minGutterWidth/2 {
+			return nil, nil, true
+		}
+		prev = g
+	}
+	for _, g := range glyphs {
+		if g.X+g.W/2 < mid {
+			left = append(left, g)
+		} else {
+			right = append(right, g)
+		}
+	}
+	return left, right, false
+}
+
+// dedupeGlyphs drops glyphs drawn twice at the same spot, which some
+// generators do to fake bold text.
+func dedupeGlyphs(glyphs []pdf.Text) []pdf.Text {
+	out := make([]pdf.Text, 0, len(glyphs))
+	for _, g := range glyphs {
+		if g.S == "" || g.S == "\n" {
+			continue
+		}
+		if n := len(out); n > 0 {
+			prev := out[n-1]
+			if prev.S == g.S && math.Abs(prev.X-g.X) < 0.5 && math.Abs(prev.Y-g.Y) < 0.5 {
+				continue
+			}
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// groupLines clusters glyphs into lines by baseline, top of the page first.
+func groupLines(glyphs []pdf.Text) []pdfLine {
+	sorted := append([]pdf.Text(nil), glyphs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if math.Abs(sorted[i].Y-sorted[j].Y) > 0.1 {
+			return sorted[i].Y > sorted[j].Y
+		}
+		return sorted[i].X < sorted[j].X
+	})
+
+	var lines []pdfLine
+	for _, g := range sorted {
+		size := math.Max(g.FontSize, 1)
+		if n := len(lines); n > 0 && math.Abs(lines[n-1].y-g.Y) <= 0.4*math.Max(size, lines[n-1].fontSize) {
+			lines[n-1].glyphs = append(lines[n-1].glyphs, g)
+			lines[n-1].fontSize = math.Max(lines[n-1].fontSize, size)
+			continue
+		}
+		lines = append(lines, pdfLine{y: g.Y, fontSize: size, glyphs: []pdf.Text{g}})
+	}
+	for i := range lines {
+		sort.SliceStable(lines[i].glyphs, func(a, b int) bool { return lines[i].glyphs[a].X < lines[i].glyphs[b].X })
+	}
+	return lines
+}
+
+// findGutter looks for an empty vertical band in the middle of the page
+// separating two columns of text. Glyph positions come straight from the
+// file, so text spread wider than the page (or at NaN or infinite
+// coordinates) is read as a single column rather than bucketed.
+func findGutter(glyphs []pdf.Text, pageWidth float64) (start, end float64, ok bool) {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, g := range glyphs {
+		minX = math.Min(minX, g.X)
+		maxX = math.Max(maxX, g.X+g.W)
+	}
+	width := maxX - minX
+	if !(width >= 4*minGutterWidth && width <= pageWidth) {
+		return 0, 0, false
+	}
+
+	buckets := make([]int, int(width)+1)
+	for _, g := range glyphs {
+		if strings.TrimSpace(g.S) == "" {
+			continue
+		}
+		from := int(g.X - minX)
+		to := int(g.X + g.W - minX)
+		for b := max(from, 0); b <= to && b < len(buckets); b++ {
+			buckets[b]++
+		}
+	}
+	allowed := int(gutterNoise * float64(len(glyphs)))
+
+	// only the middle of the page can hold a gutter; margins and
+	// indentation live at the edges
+	lo, hi := int(0.2*width), int(0.8*width)
+	bestStart, bestLen := -1, 0
+	runStart := -1
+	for b := lo; b <= hi; b++ {
+		if buckets[b] <= allowed {
+			if runStart < 0 {
+				runStart = b
+			}
+			if l := b - runStart + 1; l > bestLen {
+				bestStart, bestLen = runStart, l
+			}
+			continue
+		}
+		runStart = -1
+	}
+	if bestStart < 0 || float64(bestLen) < minGutterWidth {
+		return 0, 0, false
+	}
+
+	// both sides must carry a real share of the text, or it's a ragged
+	// margin or a single column with right-aligned dates
+	var leftCount, rightCount int
+	mid := minX + float64(bestStart) + float64(bestLen)/2
+	for _, g := range glyphs {
+		if g.X+g.W/2 < mid {
+			leftCount++
+		} else {
+			rightCount++
+		}
+	}
+	if leftCount < len(glyphs)/5 || rightCount < len(glyphs)/5 {
+		return 0, 0, false
+	}
+	return minX + float64(bestStart), minX + float64(bestStart+bestLen), true
+}
+
+// joinLines renders lines of one column, restoring paragraph breaks where
+// the vertical gap is clearly larger than the usual line spacing and
+// joining words hyphenated across lines.
+func joinLines(lines []pdfLine) string {
+	var gaps []float64
+	for i := 1; i < len(lines); i++ {
+		gaps = append(gaps, lines[i-1].y-lines[i].y)
+	}
+	spacing := 0.0
+	if len(gaps) > 0 {
+		sorted := append([]float64(nil), gaps...)
+		sort.Float64s(sorted)
+		spacing = sorted[len(sorted)/2]
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		text := lineText(line)
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			prev := b.String()
+			switch {
+			case endsWithHyphenatedWord(prev) && startsLowercase(text):
+				// "manage-" + "ment" -> "management"
+				trimmed := strings.TrimSuffix(prev, "-")
+				b.Reset()
+				b.WriteString(trimmed)
+			case spacing > 0 && gaps[i-1] > 1.5*spacing:
+				b.WriteString("\n\n")
+			default:
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// lineText joins a line's glyphs, inserting spaces where the horizontal gap
+// between glyphs is wide enough to be a word break.
+func lineText(line pdfLine) string {
+	var b strings.Builder
+	var prev *pdf.Text
+	for i := range line.glyphs {
+		g := line.glyphs[i]
+		if prev != nil {
+			gap := g.X - (prev.X + prev.W)
+			if gap > 0.15*math.Max(g.FontSize, 1) && !strings.HasSuffix(b.String(), " ") && g.S != " " {
+				b.WriteString(" ")
+			}
+		}
+		if g.S == " " && strings.HasSuffix(b.String(), " ") {
+			continue
+		}
+		b.WriteString(g.S)
+		prev = &line.glyphs[i]
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func endsWithHyphenatedWord(s string) bool {
+	runes := []rune(s)
+	n := len(runes)
+	return n >= 2 && runes[n-1] == '-' && unicode.IsLetter(runes[n-2])
+}
+
+func startsLowercase(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
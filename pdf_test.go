@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// TestExtractPDFTextLayouts checks each testdata/pdf/<name>.pdf against the
+// expected text in <name>.txt.
+func TestExtractPDFTextLayouts(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "pdf", "*.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no .pdf fixtures found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			extraction, err := extractPDFText(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("extractPDFText: %v", err)
+			}
+			checkGolden(t, strings.TrimSuffix(path, ".pdf")+".txt", extraction.Text)
+		})
+	}
+}
+
+func TestFindGutterOutOfRange(t *testing.T) {
+	column := func(x float64) []pdf.Text {
+		var glyphs []pdf.Text
+		for i := 0; i < 40; i++ {
+			glyphs = append(glyphs, pdf.Text{S: "a", X: x + float64(i%20)*5, Y: float64(700 - i/20*14), W: 5, FontSize: 10})
+		}
+		return glyphs
+	}
+	twoColumns := append(column(50), column(330)...)
+	if _, _, ok := findGutter(twoColumns, 612); !ok {
+		t.Fatal("findGutter didn't find the gutter of a two column page")
+	}
+
+	tests := []struct {
+		name string
+		x    float64
+	}{
+		{"wider than the page", 1e12},
+		{"infinite", math.Inf(1)},
+		{"NaN", math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			glyphs := append(append([]pdf.Text(nil), twoColumns...), pdf.Text{S: "x", X: tt.x, W: 5, FontSize: 10})
+			if _, _, ok := findGutter(glyphs, 612); ok {
+				t.Errorf("findGutter found a gutter with a glyph at x=%v", tt.x)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  []string
+	}{
+		{"short", "hello world", 20, []string{"hello world"}},
+		{"empty", "", 10, []string{""}},
+		{"wraps at spaces", "the quick brown fox jumps", 10, []string{"the quick", "brown fox", "jumps"}},
+		{"collapses spaces", "a   b\tc", 10, []string{"a b c"}},
+		{"exact width", "abcde fghij", 5, []string{"abcde", "fghij"}},
+		{"splits long words", "go supercalifragilistic ok", 8, []string{"go", "supercal", "ifragili", "stic ok"}},
+		{"keeps paragraphs", "first line\n\nsecond", 20, []string{"first line", "", "second"}},
+		{"counts runes", "ééééé ééééé", 5, []string{"ééééé", "ééééé"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.in, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"f(x) = y", `f\(x\) = y`},
+		{`C:\cv`, `C:\\cv`},
+		{"café", `caf\351`},
+		{"• item – €5", `\225 item \226 \2005`},
+		{"line\nbreak", `line\012break`},
+		{"日本語", "???"},
+		{"tab\there\x7f", `tab\011here\177`},
+	}
+	for _, tt := range tests {
+		if got := pdfEscape(tt.in); got != tt.want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
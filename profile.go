@@ -0,0 +1,759 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+// ResumeProfile is the canonical structured candidate profile, a subset of
+// the JSON Resume schema (https://jsonresume.org/schema).
+type ResumeProfile struct {
+	Basics       ProfileBasics        `json:"basics"`
+	Work         []ProfileWork        `json:"work,omitempty"`
+	Education    []ProfileEducation   `json:"education,omitempty"`
+	Skills       []ProfileSkill       `json:"skills,omitempty"`
+	Certificates []ProfileCertificate `json:"certificates,omitempty"`
+	Projects     []ProfileProject     `json:"projects,omitempty"`
+	Languages    []ProfileLanguage    `json:"languages,omitempty"`
+}
+
+type ProfileBasics struct {
+	Name     string           `json:"name,omitempty"`
+	Label    string           `json:"label,omitempty"`
+	Email    string           `json:"email,omitempty"`
+	Phone    string           `json:"phone,omitempty"`
+	URL      string           `json:"url,omitempty"`
+	Summary  string           `json:"summary,omitempty"`
+	Location *ProfileLocation `json:"location,omitempty"`
+	Profiles []ProfileLink    `json:"profiles,omitempty"`
+}
+
+type ProfileLocation struct {
+	Address     string `json:"address,omitempty"`
+	City        string `json:"city,omitempty"`
+	Region      string `json:"region,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+}
+
+type ProfileLink struct {
+	Network  string `json:"network"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url"`
+}
+
+type ProfileWork struct {
+	Name       string   `json:"name,omitempty"`
+	Position   string   `json:"position,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+type ProfileEducation struct {
+	Institution string `json:"institution,omitempty"`
+	Area        string `json:"area,omitempty"`
+	StudyType   string `json:"studyType,omitempty"`
+	StartDate   string `json:"startDate,omitempty"`
+	EndDate     string `json:"endDate,omitempty"`
+	Score       string `json:"score,omitempty"`
+}
+
+type ProfileSkill struct {
+	Name     string   `json:"name"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type ProfileCertificate struct {
+	Name   string `json:"name"`
+	Date   string `json:"date,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+}
+
+type ProfileProject struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	URL         string   `json:"url,omitempty"`
+}
+
+type ProfileLanguage struct {
+	Language string `json:"language"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+const (
+	sectionContact        = "contact"
+	sectionSummary        = "summary"
+	sectionExperience     = "experience"
+	sectionEducation      = "education"
+	sectionSkills         = "skills"
+	sectionCertifications = "certifications"
+	sectionProjects       = "projects"
+	sectionLanguages      = "languages"
+	sectionOther          = "other"
+)
+
+// sectionHeadings map heading lines to canonical sections. Certifications
+// come before education so "Certifications & Training" isn't read as
+// education.
+var sectionHeadings = []struct {
+	section string
+	pattern *regexp.Regexp
+}{
+	{sectionSummary, regexp.MustCompile(`(?i)^(professional |career |executive )?(summary|profile|objective|about me|about|overview)$`)},
+	{sectionCertifications, regexp.MustCompile(`(?i)^(licen[cs]es?( (and|&) certifications?)?|certifications?( (and|&) (licen[cs]es?|training|courses))?|certificates|credentials)$`)},
+	{sectionExperience, regexp.MustCompile(`(?i)^((work|professional|relevant|employment|career) (experience|history)|experience|employment|work|career)$`)},
+	{sectionEducation, regexp.MustCompile(`(?i)^(education( (and|&) (training|certifications?|qualifications))?|academic (background|qualifications|history)|qualifications|academics)$`)},
+	{sectionSkills, regexp.MustCompile(`(?i)^((technical|core|key|professional) )?(skills|competencies|expertise|skill set|skillset)( (and|&) (tools|technologies|competencies))?$|^technologies$|^tools( (and|&) technologies)?$|^tech stack$`)},
+	{sectionProjects, regexp.MustCompile(`(?i)^((personal|selected|key|side|academic) )?projects$`)},
+	{sectionLanguages, regexp.MustCompile(`(?i)^languages( spoken)?$`)},
+	{sectionOther, regexp.MustCompile(`(?i)^(interests|hobbies|references|awards|honou?rs( (and|&) awards)?|achievements|publications|volunteer(ing| experience| work)?|activities|additional information|links)$`)},
+}
+
+// resumeSection is a run of lines under one heading. Lines before the first
+// heading form the contact section.
+type resumeSection struct {
+	Name    string
+	Heading string
+	Lines   []string
+}
+
+var (
+	headingTrim      = regexp.MustCompile(`^[\s#*_=\-–—•|]+|[\s:*_=\-–—|]+$`)
+	bulletPattern    = regexp.MustCompile(`^\s*(?:[-•*·▪◦‣➢►–]|\d{1,2}[.)])\s+`)
+	phonePattern     = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{1,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]?\d{2,4}){2,4}`)
+	urlPattern       = regexp.MustCompile(`(?i)\b(?:https?://)?(?:www\.)?[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|io|dev|me|org|net|co|app|ai)(?:/[^\s,;|()]*)?`)
+	yearPattern      = regexp.MustCompile(`\b(19[5-9]\d|20\d{2})\b`)
+	institutionWords = regexp.MustCompile(`(?i)\b(university|universit[éy]|college|school|academy|institute|polytechnic|conservatory|hochschule)\b`)
+	positionWords    = regexp.MustCompile(`(?i)\b(engineer|developer|manager|lead|head|director|analyst|consultant|designer|architect|scientist|intern|assistant|officer|specialist|administrator|coordinator|associate|founder|owner|president|vp|cto|ceo|cfo|teacher|nurse|accountant|programmer|technician|researcher|editor|writer)\b`)
+	fluencyPattern   = regexp.MustCompile(`(?i)\b(native|bilingual|fluent|proficient|advanced|intermediate|basic|beginner|conversational|elementary|professional working|full professional|limited working|mother tongue|[abc][12])\b`)
+	contactWords     = regexp.MustCompile(`(?i)(@|https?://|www\.|linkedin|github|\d{3})`)
+)
+
+// sectionFor returns the canonical section a line heads, if it is a
+// heading.
+func sectionFor(line string) (string, bool) {
+	trimmed := headingTrim.ReplaceAllString(strings.TrimSpace(line), "")
+	if trimmed == "" || len(trimmed) > 40 || len(strings.Fields(trimmed)) > 5 {
+		return "", false
+	}
+	for _, h := range sectionHeadings {
+		if h.pattern.MatchString(trimmed) {
+			return h.section, true
+		}
+	}
+	return "", false
+}
+
+// segmentResume splits extracted resume text into sections.
+func segmentResume(text string) []resumeSection {
+	sections := []resumeSection{{Name: sectionContact}}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\f", ""), " \t")
+		if name, ok := sectionFor(line); ok {
+			sections = append(sections, resumeSection{Name: name, Heading: strings.TrimSpace(line)})
+			continue
+		}
+		current := &sections[len(sections)-1]
+		current.Lines = append(current.Lines, line)
+	}
+	return sections
+}
+
+// buildProfile segments resume text and maps the sections onto a
+// ResumeProfile.
+func buildProfile(text string, now time.Time) *ResumeProfile {
+	profile := &ResumeProfile{}
+	sections := segmentResume(text)
+	for _, s := range sections {
+		switch s.Name {
+		case sectionContact:
+			profile.Basics = parseBasics(s.Lines)
+		case sectionSummary:
+			profile.Basics.Summary = strings.TrimSpace(joinParagraph(s.Lines))
+		case sectionExperience:
+			profile.Work = append(profile.Work, parseWork(s.Lines, now)...)
+		case sectionEducation:
+			profile.Education = append(profile.Education, parseEducation(s.Lines, now)...)
+		case sectionSkills:
+			profile.Skills = append(profile.Skills, parseSkills(s.Lines)...)
+		case sectionCertifications:
+			profile.Certificates = append(profile.Certificates, parseCertificates(s.Lines)...)
+		case sectionProjects:
+			profile.Projects = append(profile.Projects, parseProjects(s.Lines)...)
+		case sectionLanguages:
+			profile.Languages = append(profile.Languages, parseLanguages(s.Lines)...)
+		}
+	}
+
+	// contact details are sometimes in a sidebar or footer rather than the
+	// header
+	if profile.Basics.Email == "" {
+		profile.Basics.Email = emailPattern.FindString(text)
+	}
+	if profile.Basics.Phone == "" {
+		profile.Basics.Phone = findPhone(text)
+	}
+	if len(profile.Basics.Profiles) == 0 {
+		profile.Basics.Profiles, _ = findLinks(text)
+	}
+	return profile
+}
+
+func parseBasics(lines []string) ProfileBasics {
+	var basics ProfileBasics
+	text := strings.Join(lines, "\n")
+	basics.Email = emailPattern.FindString(text)
+	basics.Phone = findPhone(text)
+	basics.Profiles, basics.URL = findLinks(text)
+
+	var plain []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || contactWords.MatchString(line) {
+			continue
+		}
+		plain = append(plain, line)
+	}
+	if len(plain) > 0 && len(strings.Fields(plain[0])) <= 5 {
+		basics.Name = plain[0]
+		plain = plain[1:]
+	}
+	if len(plain) > 0 && len(strings.Fields(plain[0])) <= 8 && !strings.Contains(plain[0], ",") {
+		basics.Label = plain[0]
+		plain = plain[1:]
+	}
+	// a remaining short "City, Region" line is the location
+	for _, line := range plain {
+		parts := strings.Split(line, ",")
+		if len(parts) >= 2 && len(parts) <= 3 && len(line) <= 50 {
+			loc := &ProfileLocation{City: strings.TrimSpace(parts[0]), Region: strings.TrimSpace(parts[len(parts)-1])}
+			basics.Location = loc
+			break
+		}
+	}
+	return basics
+}
+
+func findPhone(text string) string {
+	for _, m := range phonePattern.FindAllString(text, -1) {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		// long enough for a phone number, and not a date range
+		if digits >= 7 && digits <= 15 && !dateRangePattern.MatchString(m) {
+			return strings.TrimSpace(m)
+		}
+	}
+	return ""
+}
+
+var profileNetworks = []struct{ domain, name string }{
+	{"linkedin.", "LinkedIn"},
+	{"github.", "GitHub"},
+	{"gitlab.", "GitLab"},
+	{"twitter.", "Twitter"},
+	{"x.com/", "Twitter"},
+	{"behance.", "Behance"},
+	{"dribbble.", "Dribbble"},
+	{"stackoverflow.", "Stack Overflow"},
+	{"medium.", "Medium"},
+}
+
+// findLinks returns the social profiles found in text, and the first other
+// link as the candidate's website.
+func findLinks(text string) (profiles []ProfileLink, website string) {
+	seen := map[string]bool{}
+	for _, m := range urlPattern.FindAllString(text, -1) {
+		link := strings.TrimRight(m, ".")
+		lower := strings.ToLower(link)
+		if seen[lower] || emailPattern.MatchString(link) {
+			continue
+		}
+		seen[lower] = true
+		network := ""
+		for _, n := range profileNetworks {
+			if strings.Contains(lower, n.domain) {
+				network = n.name
+				break
+			}
+		}
+		if !strings.HasPrefix(lower, "http") {
+			link = "https://" + link
+		}
+		if network == "" {
+			// the domain of an email address isn't a website
+			if website == "" && !strings.Contains(text, "@"+m) {
+				website = link
+			}
+			continue
+		}
+		parts := strings.Split(strings.TrimRight(link, "/"), "/")
+		username := ""
+		if len(parts) > 3 {
+			username = parts[len(parts)-1]
+		}
+		profiles = append(profiles, ProfileLink{Network: network, Username: username, URL: link})
+	}
+	return profiles, website
+}
+
+// entryBlocks splits a section into entries. A line carrying a date range
+// starts a new entry; when the dates sit on a line of their own the entry
+// starts at the line before.
+func entryBlocks(lines []string) [][]string {
+	var starts []int
+	for i, line := range lines {
+		if !dateRangePattern.MatchString(line) {
+			continue
+		}
+		start := i
+		if isDateOnlyLine(line) && i > 0 && strings.TrimSpace(lines[i-1]) != "" && !bulletPattern.MatchString(lines[i-1]) {
+			start = i - 1
+		}
+		// a title line directly above the dated line belongs to the entry
+		if start == i && i > 0 && isTitleLine(lines[i-1]) && !dateRangePattern.MatchString(lines[i-1]) {
+			start = i - 1
+		}
+		if len(starts) == 0 || start > starts[len(starts)-1] {
+			starts = append(starts, start)
+		}
+	}
+	if len(starts) == 0 {
+		return paragraphs(lines)
+	}
+	// titles above the first dated line belong to the first entry
+	starts[0] = 0
+	var blocks [][]string
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		blocks = append(blocks, lines[start:end])
+	}
+	return blocks
+}
+
+// isTitleLine reports whether a line looks like an entry title rather than
+// part of a description.
+func isTitleLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && !bulletPattern.MatchString(line) && len(line) <= 80 && !strings.HasSuffix(line, ".")
+}
+
+// paragraphs splits lines at blank lines.
+func paragraphs(lines []string) [][]string {
+	var blocks [][]string
+	var current []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+func parseWork(lines []string, now time.Time) []ProfileWork {
+	var work []ProfileWork
+	for _, block := range entryBlocks(lines) {
+		var entry ProfileWork
+		var titles, description []string
+		for _, line := range block {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if m := dateRangePattern.FindStringSubmatch(trimmed); m != nil && entry.StartDate == "" {
+				if start, end, current, ok := parseDateRange(m[1], m[2], now); ok {
+					entry.StartDate = formatMonth(start)
+					if !current {
+						entry.EndDate = formatMonth(end)
+					}
+				}
+				trimmed = strings.Trim(dateRangePattern.ReplaceAllString(trimmed, ""), " \t|,()-–—")
+				if trimmed == "" {
+					continue
+				}
+			}
+			switch {
+			case bulletPattern.MatchString(trimmed):
+				entry.Highlights = append(entry.Highlights, bulletPattern.ReplaceAllString(trimmed, ""))
+			case len(description) == 0 && len(entry.Highlights) == 0 && len(titles) < 2 && isTitleLine(trimmed):
+				titles = append(titles, trimmed)
+			default:
+				description = append(description, trimmed)
+			}
+		}
+		entry.Position, entry.Name = splitTitle(titles)
+		entry.Summary = joinParagraph(description)
+		if entry.Position != "" || entry.Name != "" || entry.StartDate != "" {
+			work = append(work, entry)
+		}
+	}
+	return work
+}
+
+var titleSeparators = regexp.MustCompile(`\s+(?:at|@)\s+|\s*[|,]\s*|\s+[-–—]\s+`)
+
+// splitTitle separates the position and organisation named in an entry's
+// title lines ("Senior Engineer at Acme", "Acme | Senior Engineer", or one
+// per line).
+func splitTitle(titles []string) (position, organisation string) {
+	var parts []string
+	for _, t := range titles {
+		for _, p := range titleSeparators.Split(t, 3) {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		if positionWords.MatchString(parts[0]) {
+			return parts[0], ""
+		}
+		return "", parts[0]
+	}
+	if !positionWords.MatchString(parts[0]) && positionWords.MatchString(parts[1]) {
+		return parts[1], parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+func parseEducation(lines []string, now time.Time) []ProfileEducation {
+	var education []ProfileEducation
+	for _, block := range entryBlocks(lines) {
+		var entry ProfileEducation
+		for _, line := range block {
+			trimmed := strings.TrimSpace(bulletPattern.ReplaceAllString(line, ""))
+			if trimmed == "" {
+				continue
+			}
+			if m := dateRangePattern.FindStringSubmatch(trimmed); m != nil && entry.StartDate == "" {
+				if start, end, current, ok := parseDateRange(m[1], m[2], now); ok {
+					entry.StartDate = formatMonth(start)
+					if !current {
+						entry.EndDate = formatMonth(end)
+					}
+				}
+			} else if y := yearPattern.FindString(trimmed); y != "" && entry.EndDate == "" && entry.StartDate == "" {
+				entry.EndDate = y
+			}
+			if strings.Contains(strings.ToLower(trimmed), "gpa") || strings.Contains(strings.ToLower(trimmed), "grade") {
+				entry.Score = trimmed
+				continue
+			}
+			clean := strings.Trim(yearPattern.ReplaceAllString(dateRangePattern.ReplaceAllString(trimmed, ""), ""), " \t|,()-–—")
+			if clean == "" {
+				continue
+			}
+			if entry.Institution == "" && institutionWords.MatchString(clean) {
+				entry.Institution = clean
+				continue
+			}
+			if entry.StudyType == "" {
+				if _, name := highestDegree(clean); name != "" {
+					entry.StudyType = clean
+					if i := strings.Index(strings.ToLower(clean), " in "); i >= 0 {
+						entry.StudyType = strings.TrimSpace(clean[:i])
+						entry.Area = strings.TrimSpace(clean[i+4:])
+					}
+					continue
+				}
+			}
+			if entry.Institution == "" {
+				entry.Institution = clean
+			}
+		}
+		if entry.Institution != "" || entry.StudyType != "" {
+			education = append(education, entry)
+		}
+	}
+	return education
+}
+
+var listSeparators = regexp.MustCompile(`\s*[,;|•·]\s*`)
+
+func parseSkills(lines []string) []ProfileSkill {
+	var skills []ProfileSkill
+	for _, line := range lines {
+		line = strings.TrimSpace(bulletPattern.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		// "Languages: Go, Python" is a category with keywords
+		if category, rest, ok := strings.Cut(line, ":"); ok && len(strings.Fields(category)) <= 4 && strings.TrimSpace(rest) != "" {
+			skills = append(skills, ProfileSkill{Name: strings.TrimSpace(category), Keywords: nonEmpty(listSeparators.Split(rest, -1))})
+			continue
+		}
+		for _, item := range nonEmpty(listSeparators.Split(line, -1)) {
+			skills = append(skills, ProfileSkill{Name: item})
+		}
+	}
+	return skills
+}
+
+func parseCertificates(lines []string) []ProfileCertificate {
+	var certificates []ProfileCertificate
+	for _, line := range lines {
+		line = strings.TrimSpace(bulletPattern.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		cert := ProfileCertificate{Date: yearPattern.FindString(line)}
+		name := strings.Trim(yearPattern.ReplaceAllString(line, ""), " \t|,()-–—")
+		if before, after, ok := strings.Cut(name, " - "); ok {
+			name, cert.Issuer = strings.TrimSpace(before), strings.TrimSpace(after)
+		} else if before, after, ok := strings.Cut(name, ", "); ok && len(strings.Fields(after)) <= 4 {
+			name, cert.Issuer = strings.TrimSpace(before), strings.TrimSpace(after)
+		}
+		cert.Name = name
+		if cert.Name != "" {
+			certificates = append(certificates, cert)
+		}
+	}
+	return certificates
+}
+
+func parseProjects(lines []string) []ProfileProject {
+	var projects []ProfileProject
+	var current *ProfileProject
+	var description []string
+	finish := func() {
+		if current != nil {
+			current.Description = joinParagraph(description)
+			projects = append(projects, *current)
+		}
+		current, description = nil, nil
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case bulletPattern.MatchString(trimmed) && current != nil:
+			current.Highlights = append(current.Highlights, bulletPattern.ReplaceAllString(trimmed, ""))
+		case current == nil || isTitleLine(trimmed) && (len(description) > 0 || len(current.Highlights) > 0):
+			finish()
+			name := bulletPattern.ReplaceAllString(trimmed, "")
+			current = &ProfileProject{Name: name}
+			if url := urlPattern.FindString(name); url != "" {
+				current.URL = url
+			}
+		default:
+			description = append(description, trimmed)
+			if current.URL == "" {
+				current.URL = urlPattern.FindString(trimmed)
+			}
+		}
+	}
+	finish()
+	return projects
+}
+
+var languageSeparators = regexp.MustCompile(`\s*[,;|•]\s*`)
+
+func parseLanguages(lines []string) []ProfileLanguage {
+	var languages []ProfileLanguage
+	for _, line := range lines {
+		line = strings.TrimSpace(bulletPattern.ReplaceAllString(line, ""))
+		for _, item := range nonEmpty(languageSeparators.Split(line, -1)) {
+			lang := ProfileLanguage{Language: item}
+			if m := fluencyPattern.FindStringIndex(item); m != nil {
+				lang.Fluency = strings.TrimSpace(item[m[0]:])
+				lang.Language = strings.Trim(item[:m[0]], " \t:-–()")
+				lang.Fluency = strings.Trim(lang.Fluency, " ()")
+			}
+			if lang.Language != "" {
+				languages = append(languages, lang)
+			}
+		}
+	}
+	return languages
+}
+
+// joinParagraph joins wrapped lines into running text, keeping paragraph
+// breaks.
+func joinParagraph(lines []string) string {
+	var out []string
+	for _, p := range paragraphs(lines) {
+		for i := range p {
+			p[i] = strings.TrimSpace(p[i])
+		}
+		out = append(out, strings.Join(p, " "))
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// saveResumeProfile stores the structured profile for a resume. The profile
+// is a by-product of the analysis, so failures are logged, not returned.
+func saveResumeProfile(ctx context.Context, workerConfig *WorkerConfig, resume database.Resume, profile *ResumeProfile) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		log.Printf("⚠️ Failed to marshal profile for %s: %v", resume.ObjectKey, err)
+		return
+	}
+	_, err = retry(3, func() (any, error) {
+		return nil, workerConfig.DB.UpsertResumeProfile(ctx, database.UpsertResumeProfileParams{
+			ResumeID:  resume.ID,
+			SessionID: resume.SessionID,
+			Profile:   data,
+		})
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to save profile for %s: %v", resume.ObjectKey, err)
+	}
+}
+
+// renderProfile writes a profile out as plain resume text, with headings and
+// date ranges the segmenter and experience parser recognise.
+func renderProfile(p *ResumeProfile) string {
+	var b strings.Builder
+	line := func(parts ...string) {
+		if s := strings.Join(nonEmpty(parts), " | "); s != "" {
+			b.WriteString(s + "\n")
+		}
+	}
+	section := func(heading string) {
+		b.WriteString("\n" + heading + "\n")
+	}
+	bullets := func(items []string) {
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				b.WriteString("- " + item + "\n")
+			}
+		}
+	}
+
+	line(p.Basics.Name)
+	line(p.Basics.Label)
+	if loc := p.Basics.Location; loc != nil {
+		line(strings.Join(nonEmpty([]string{loc.Address, loc.City, loc.Region, loc.CountryCode}), ", "))
+	}
+	contact := []string{p.Basics.Email, p.Basics.Phone, p.Basics.URL}
+	for _, link := range p.Basics.Profiles {
+		contact = append(contact, link.URL)
+	}
+	line(contact...)
+
+	if p.Basics.Summary != "" {
+		section("Summary")
+		line(p.Basics.Summary)
+	}
+	if len(p.Work) > 0 {
+		section("Work Experience")
+		for _, w := range p.Work {
+			b.WriteString("\n")
+			title := w.Position
+			if w.Name != "" {
+				title = strings.TrimSpace(title + " at " + w.Name)
+			}
+			line(strings.TrimPrefix(title, "at "))
+			line(renderDateRange(w.StartDate, w.EndDate, true))
+			line(w.Summary)
+			bullets(w.Highlights)
+		}
+	}
+	if len(p.Education) > 0 {
+		section("Education")
+		for _, e := range p.Education {
+			b.WriteString("\n")
+			line(e.Institution)
+			degree := e.StudyType
+			if e.Area != "" {
+				degree = strings.TrimSpace(degree + " in " + e.Area)
+			}
+			line(strings.TrimPrefix(degree, "in "))
+			line(renderDateRange(e.StartDate, e.EndDate, false))
+			line(e.Score)
+		}
+	}
+	if len(p.Skills) > 0 {
+		section("Skills")
+		for _, s := range p.Skills {
+			name := s.Name
+			if s.Level != "" {
+				name += " (" + s.Level + ")"
+			}
+			if len(s.Keywords) > 0 {
+				name += ": " + strings.Join(s.Keywords, ", ")
+			}
+			bullets([]string{name})
+		}
+	}
+	if len(p.Certificates) > 0 {
+		section("Certifications")
+		for _, c := range p.Certificates {
+			bullets([]string{strings.Join(nonEmpty([]string{c.Name, c.Issuer, renderDate(c.Date)}), " - ")})
+		}
+	}
+	if len(p.Projects) > 0 {
+		section("Projects")
+		for _, pr := range p.Projects {
+			b.WriteString("\n")
+			line(pr.Name, pr.URL)
+			line(pr.Description)
+			bullets(pr.Highlights)
+		}
+	}
+	if len(p.Languages) > 0 {
+		section("Languages")
+		for _, l := range p.Languages {
+			if l.Fluency != "" {
+				bullets([]string{l.Language + " (" + l.Fluency + ")"})
+			} else {
+				bullets([]string{l.Language})
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// renderDateRange formats ISO 8601 profile dates as "MM/YYYY - MM/YYYY". A
+// missing end date on a job means it is current.
+func renderDateRange(start, end string, openEnded bool) string {
+	start, end = renderDate(start), renderDate(end)
+	switch {
+	case start == "" && end == "":
+		return ""
+	case start == "":
+		return end
+	case end == "" && openEnded:
+		return start + " - Present"
+	case end == "":
+		return start
+	}
+	return start + " - " + end
+}
+
+// renderDate turns "2020-01-15" or "2020-01" into "01/2020"; bare years and
+// anything else are kept as is.
+func renderDate(date string) string {
+	parts := strings.Split(strings.TrimSpace(date), "-")
+	if len(parts) >= 2 && len(parts[0]) == 4 && len(parts[1]) == 2 {
+		return parts[1] + "/" + parts[0]
+	}
+	return strings.TrimSpace(date)
+}
@@ -30,3 +30,24 @@ Return only valid JSON. Do not include explanations, markdown, or text before or
 Your response must be a single JSON object.
 	`
 }
+
+func knockoutPrompt() string {
+	return `
+	You screen resumes against mandatory hiring requirements.
+
+You receive a JSON array of rules, each with an "id", a "type" and a "value", followed by the resume text.
+For every rule decide whether the resume clearly satisfies it.
+
+Return your result as a structured JSON object in this format:
+
+{
+  "results": [
+    {"id": string, "passed": boolean, "reason": string}
+  ]
+}
+
+Base every decision only on the provided resume text. If the resume does not mention the requirement, the rule is not passed.
+Keep each reason to one short sentence.
+Return only valid JSON. Do not include explanations, markdown, or text before or after the JSON.
+	`
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	errorCodeUnreadable = "unreadable_document"
+
+	// thresholds below which extracted text isn't worth sending to the agent
+	minTextLength      = 200
+	minPrintableRatio  = 0.9
+	minDictionaryRatio = 0.45
+	minAvgLineLength   = 4.0
+	maxMojibakeRatio   = 0.02
+)
+
+// commonWords are frequent English words seen in resumes, on top of the
+// stopword and skill lists used elsewhere.
+var commonWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`experience work worked working team teams project projects
+		managed manage management developed develop development design designed
+		lead led senior junior engineer engineering developer software manager
+		company business customer customers client clients service services
+		system systems data application applications product products support
+		skills skill education university college degree bachelor master school
+		years year month months present current responsible responsibilities
+		including improved improve increased reduced built create created
+		using used new high performance process processes analysis report
+		reports reporting technical technology technologies tools across
+		multiple various key role roles within based well also more other
+		professional summary objective certification certifications contact
+		email phone address languages language english references available
+		achievements award awards training member volunteer interests hobbies
+		implemented implementation maintained maintain delivered deliver strong
+		knowledge ability excellent communication problem solving planning
+		sales marketing finance operations research quality testing production
+		web mobile cloud platform infrastructure security network database
+		intern internship assistant coordinator specialist analyst consultant
+		director officer administrator architect designer scientist teacher
+		national international global local time first best full part`) {
+		commonWords[w] = true
+	}
+}
+
+// TextQuality describes how usable extracted text is.
+type TextQuality struct {
+	// Score is 0-1, the mean of the individual checks.
+	Score float64 `json:"score"`
+	// Length counts non-space characters.
+	Length          int      `json:"length"`
+	PrintableRatio  float64  `json:"printable_ratio"`
+	DictionaryRatio float64  `json:"dictionary_ratio"`
+	AvgLineLength   float64  `json:"avg_line_length"`
+	MojibakeRatio   float64  `json:"mojibake_ratio,omitempty"`
+	Unreadable      bool     `json:"unreadable"`
+	Reasons         []string `json:"reasons,omitempty"`
+}
+
+// assessTextQuality checks extracted text for the failure modes of broken
+// extraction: too little text, control or replacement characters, mojibake
+// from wrong encodings, tokens that aren't words, and one character per
+// line layouts.
+func assessTextQuality(text string) TextQuality {
+	var q TextQuality
+	var total, printable, mojibake int
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == utf8.RuneError || unicode.In(r, unicode.Co) || unicode.IsControl(r) {
+			continue
+		}
+		printable++
+		// "Ã©", "â€™" and friends: UTF-8 read as Latin-1 / cp1252
+		if (r == 'Ã' || r == 'â' || r == 'Â') && i+utf8.RuneLen(r) < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			if next >= 0x80 && next <= 0xBF || next == '€' || next == '™' || next == '‚' || next == '¢' {
+				mojibake++
+			}
+		}
+	}
+	q.Length = total
+	if total == 0 {
+		q.Unreadable = true
+		q.Reasons = []string{"no text"}
+		return q
+	}
+	q.PrintableRatio = round2(float64(printable) / float64(total))
+	q.MojibakeRatio = round2(float64(mojibake) / float64(total))
+
+	var words, known int
+	for _, token := range wordPattern.FindAllString(strings.ToLower(text), 5000) {
+		words++
+		if isDictionaryWord(token) {
+			known++
+		}
+	}
+	if words > 0 {
+		q.DictionaryRatio = round2(float64(known) / float64(words))
+	}
+
+	var lines, lineChars int
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines++
+			lineChars += utf8.RuneCountInString(line)
+		}
+	}
+	if lines > 0 {
+		q.AvgLineLength = round2(float64(lineChars) / float64(lines))
+	}
+
+	checks := []struct {
+		ok     bool
+		score  float64
+		reason string
+	}{
+		{q.Length >= minTextLength, math.Min(float64(q.Length)/minTextLength, 1),
+			fmt.Sprintf("only %d characters of text", q.Length)},
+		{q.PrintableRatio >= minPrintableRatio, q.PrintableRatio,
+			fmt.Sprintf("%.0f%% unprintable characters", (1-q.PrintableRatio)*100)},
+		{q.DictionaryRatio >= minDictionaryRatio, math.Min(q.DictionaryRatio/minDictionaryRatio, 1),
+			fmt.Sprintf("only %.0f%% of tokens are words", q.DictionaryRatio*100)},
+		{q.AvgLineLength >= minAvgLineLength, math.Min(q.AvgLineLength/minAvgLineLength, 1),
+			fmt.Sprintf("average line is %.1f characters", q.AvgLineLength)},
+		{q.MojibakeRatio <= maxMojibakeRatio, 1 - math.Min(q.MojibakeRatio/maxMojibakeRatio, 1)/2,
+			"text looks like mis-decoded UTF-8"},
+	}
+	var sum float64
+	for _, c := range checks {
+		sum += c.score
+		if !c.ok {
+			q.Unreadable = true
+			q.Reasons = append(q.Reasons, c.reason)
+		}
+	}
+	q.Score = round2(sum / float64(len(checks)))
+	return q
+}
+
+// isDictionaryWord accepts known words, and otherwise tokens shaped like
+// words: letters with a vowel and no long consonant runs for Latin script,
+// any letter run for other scripts.
+func isDictionaryWord(token string) bool {
+	if commonWords[token] || stopwords[token] || knownSkills[token] {
+		return true
+	}
+	for _, set := range languageStopwords {
+		if set[token] {
+			return true
+		}
+	}
+	n := utf8.RuneCountInString(token)
+	if n < 2 || n > 25 {
+		return false
+	}
+	latin, vowels, run, maxRun := 0, 0, 0, 0
+	for _, r := range token {
+		if !unicode.Is(unicode.Latin, r) {
+			continue
+		}
+		latin++
+		if strings.ContainsRune("aeiouyàáâäãåèéêëìíîïòóôöõùúûüæœø", r) {
+			vowels++
+			run = 0
+			continue
+		}
+		run++
+		maxRun = max(maxRun, run)
+	}
+	if latin == 0 {
+		return true
+	}
+	return vowels > 0 && maxRun <= 4
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const cleanResume = `Jane Doe
+Senior Software Engineer
+
+Summary
+Software engineer with eight years of experience building data platforms
+and web applications for customers across finance and retail.
+
+Experience
+Senior Engineer, Acme Ltd, Jan 2019 - present
+Led a team of five developers and improved reporting performance.
+Designed and maintained services using Go, PostgreSQL and Kubernetes.
+
+Education
+Bachelor of Science in Computer Science, University of Lagos`
+
+func TestAssessTextQuality(t *testing.T) {
+	mojibake := strings.NewReplacer("e", "Ã©", "'", "â€™").Replace(cleanResume)
+	tests := []struct {
+		name       string
+		text       string
+		unreadable bool
+		// reason is a substring of one of the reported reasons
+		reason string
+	}{
+		{"clean text", cleanResume, false, ""},
+		{"clean non-Latin text", strings.Repeat("Опытный инженер программного обеспечения в банке\n", 6), false, ""},
+		{"empty", " \n\t", true, "no text"},
+		{"short text", "Jane Doe\nEngineer", true, "only 15 characters"},
+		{"mojibake", mojibake, true, "mis-decoded UTF-8"},
+		{"control characters", cleanResume + strings.Repeat("\x00\x01\x02", 60), true, "unprintable"},
+		{"replacement characters", cleanResume + strings.Repeat("�", 80), true, "unprintable"},
+		{"low dictionary ratio", strings.Repeat("xkcd qzrtp bcdfgh zzkv wq pltkrs mnbvcx\n", 10), true, "tokens are words"},
+		{"one character per line", strings.Join(strings.Split(strings.ReplaceAll(cleanResume, "\n", ""), ""), "\n"), true, "average line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := assessTextQuality(tt.text)
+			if q.Unreadable != tt.unreadable {
+				t.Fatalf("Unreadable = %v, want %v (reasons %q, quality %+v)", q.Unreadable, tt.unreadable, q.Reasons, q)
+			}
+			if !tt.unreadable {
+				if len(q.Reasons) > 0 || q.Score != 1 {
+					t.Errorf("readable text has score %v and reasons %q", q.Score, q.Reasons)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(q.Reasons, "; "), tt.reason) {
+				t.Errorf("Reasons = %q, want one containing %q", q.Reasons, tt.reason)
+			}
+			if q.Score >= 1 {
+				t.Errorf("unreadable text has score %v", q.Score)
+			}
+		})
+	}
+}
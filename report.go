@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+// sessionReport is the downloadable summary of a session's analysis.
+type sessionReport struct {
+	Session   Session
+	Generated time.Time
+	Method    string
+	Rubric    []reportWeight
+	// Ranked are the scored candidates, best first; knocked out and failed
+	// files are listed separately.
+	Ranked     []reportCandidate
+	KnockedOut []reportCandidate
+	Failed     []reportCandidate
+}
+
+type reportWeight struct {
+	Name    string
+	Percent int
+}
+
+type reportCandidate struct {
+	Rank           int
+	Label          string
+	Score          int
+	Recommendation string
+	Summary        string
+	Strengths      []string
+	Gaps           []string
+	// Reason is why the candidate was knocked out or the file failed.
+	Reason string
+}
+
+// rubricOrder lists the score components as they're presented.
+var rubricOrder = []string{componentLLM, componentKeywords, componentExperience, componentMustHave}
+
+var rubricDescriptions = map[string]string{
+	componentLLM:        "Agent assessment of the resume against the job description",
+	componentKeywords:   "Job description terms found in the resume",
+	componentExperience: "Years of experience against the years required",
+	componentMustHave:   "Required skills present",
+}
+
+func buildSessionReport(currentSession Session, results []AnalysesResult, workerConfig *WorkerConfig, now time.Time) sessionReport {
+	report := sessionReport{Session: currentSession, Generated: now, Method: workerConfig.ScoringMode}
+
+	var total float64
+	for _, name := range rubricOrder {
+		total += workerConfig.ScoreWeights[name]
+	}
+	for _, name := range rubricOrder {
+		if weight := workerConfig.ScoreWeights[name]; weight > 0 && total > 0 {
+			report.Rubric = append(report.Rubric, reportWeight{Name: rubricDescriptions[name], Percent: int(math.Round(100 * weight / total))})
+		}
+	}
+
+	for i, result := range results {
+		candidate := reportCandidate{
+			Label:          candidateLabel(result, i),
+			Score:          result.MatchScore,
+			Recommendation: result.Recomendation,
+			Summary:        result.Summary,
+			Strengths:      dedupe(append(append([]string{}, result.RelevantSkills...), result.RelevantExperiences...)),
+			Gaps:           dedupe(append(append([]string{}, result.MissingSkills...), mustHavesMissing(result)...)),
+		}
+		switch {
+		case result.IsErrorResult:
+			candidate.Reason = result.Error
+			report.Failed = append(report.Failed, candidate)
+		case result.KnockedOut:
+			candidate.Reason = result.KnockoutReason
+			report.KnockedOut = append(report.KnockedOut, candidate)
+		default:
+			report.Ranked = append(report.Ranked, candidate)
+		}
+	}
+	sort.SliceStable(report.Ranked, func(i, j int) bool { return report.Ranked[i].Score > report.Ranked[j].Score })
+	for i := range report.Ranked {
+		report.Ranked[i].Rank = i + 1
+	}
+	return report
+}
+
+// candidateLabel names a result in the report: by email, else by file.
+func candidateLabel(result AnalysesResult, index int) string {
+	switch {
+	case result.CandidateEmail != "":
+		return result.CandidateEmail
+	case result.Filename != "":
+		return result.Filename
+	default:
+		return fmt.Sprintf("Resume %d", index+1)
+	}
+}
+
+func mustHavesMissing(result AnalysesResult) []string {
+	if result.ScoreBreakdown == nil {
+		return nil
+	}
+	return result.ScoreBreakdown.MustHavesMissing
+}
+
+// dedupe drops blank and repeated (case-insensitively) entries.
+func dedupe(items []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, item := range items {
+		key := strings.ToLower(strings.TrimSpace(item))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, strings.TrimSpace(item))
+	}
+	return out
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Session.JobTitle}} – candidate report</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; max-width: 860px; margin: 2em auto; color: #222; }
+h1 { margin-bottom: 0; }
+.meta { color: #666; }
+.candidate { border-top: 1px solid #ddd; padding: 0.5em 0; }
+.score { font-weight: bold; }
+table { border-collapse: collapse; }
+td { padding: 0.2em 1em 0.2em 0; }
+</style>
+</head>
+<body>
+<h1>{{.Session.JobTitle}}</h1>
+<p class="meta">{{.Session.Name}} · generated {{.Generated.Format "2 Jan 2006 15:04 MST"}} · {{len .Ranked}} ranked, {{len .KnockedOut}} knocked out, {{len .Failed}} not analysed</p>
+
+<h2>Scoring rubric</h2>
+<p>Scores are out of 100{{if eq .Method "lexical"}}, from keyword matching only{{end}}.</p>
+{{if .Rubric}}<table>
+{{range .Rubric}}<tr><td>{{.Name}}</td><td>{{.Percent}}%</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Ranked candidates</h2>
+{{range .Ranked}}<div class="candidate">
+<h3>#{{.Rank}} {{.Label}} <span class="score">{{.Score}}/100</span></h3>
+{{if .Recommendation}}<p><strong>Recommendation:</strong> {{.Recommendation}}</p>{{end}}
+{{if .Summary}}<p>{{.Summary}}</p>{{end}}
+{{if .Strengths}}<p><strong>Strengths</strong></p><ul>{{range .Strengths}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Gaps}}<p><strong>Gaps</strong></p><ul>{{range .Gaps}}<li>{{.}}</li>{{end}}</ul>{{end}}
+</div>
+{{else}}<p>No candidates were scored.</p>
+{{end}}
+{{if .KnockedOut}}<h2>Knocked out</h2>
+<ul>{{range .KnockedOut}}<li>{{.Label}}: {{.Reason}}</li>{{end}}</ul>{{end}}
+{{if .Failed}}<h2>Not analysed</h2>
+<ul>{{range .Failed}}<li>{{.Label}}: {{.Reason}}</li>{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+func renderReportHTML(report sessionReport) ([]byte, error) {
+	var out bytes.Buffer
+	if err := reportTemplate.Execute(&out, report); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// renderReportPDF lays out the same content as the HTML report.
+func renderReportPDF(report sessionReport) []byte {
+	pdf := newPDFWriter()
+	pdf.Text(report.Session.JobTitle, 20, 0, true)
+	pdf.Text(fmt.Sprintf("%s · generated %s · %d ranked, %d knocked out, %d not analysed", report.Session.Name, report.Generated.Format("2 Jan 2006 15:04 MST"), len(report.Ranked), len(report.KnockedOut), len(report.Failed)), 9, 0, false)
+
+	pdf.Space(12)
+	pdf.Text("Scoring rubric", 14, 0, true)
+	if report.Method == scoringMethodLexical {
+		pdf.Text("Scores are out of 100, from keyword matching only.", 10, 0, false)
+	} else {
+		pdf.Text("Scores are out of 100.", 10, 0, false)
+	}
+	for _, weight := range report.Rubric {
+		pdf.Text(fmt.Sprintf("%s: %d%%", weight.Name, weight.Percent), 10, 12, false)
+	}
+
+	pdf.Space(12)
+	pdf.Text("Ranked candidates", 14, 0, true)
+	if len(report.Ranked) == 0 {
+		pdf.Text("No candidates were scored.", 10, 0, false)
+	}
+	for _, candidate := range report.Ranked {
+		pdf.Space(6)
+		pdf.Text(fmt.Sprintf("#%d %s  %d/100", candidate.Rank, candidate.Label, candidate.Score), 12, 0, true)
+		if candidate.Recommendation != "" {
+			pdf.Text("Recommendation: "+candidate.Recommendation, 10, 0, false)
+		}
+		if candidate.Summary != "" {
+			pdf.Text(candidate.Summary, 10, 0, false)
+		}
+		for _, section := range []struct {
+			title string
+			items []string
+		}{{"Strengths", candidate.Strengths}, {"Gaps", candidate.Gaps}} {
+			if len(section.items) == 0 {
+				continue
+			}
+			pdf.Text(section.title, 10, 0, true)
+			for _, item := range section.items {
+				pdf.Text("• "+item, 10, 12, false)
+			}
+		}
+	}
+
+	for _, section := range []struct {
+		title      string
+		candidates []reportCandidate
+	}{{"Knocked out", report.KnockedOut}, {"Not analysed", report.Failed}} {
+		if len(section.candidates) == 0 {
+			continue
+		}
+		pdf.Space(12)
+		pdf.Text(section.title, 14, 0, true)
+		for _, candidate := range section.candidates {
+			pdf.Text(fmt.Sprintf("%s: %s", candidate.Label, candidate.Reason), 10, 0, false)
+		}
+	}
+	return pdf.Bytes()
+}
+
+// reportKey is where a session's report is stored, by extension.
+func reportKey(sessionID fmt.Stringer, ext string) string {
+	return fmt.Sprintf("reports/%s/report.%s", sessionID, ext)
+}
+
+// publishReport renders the session report, uploads it and records its keys
+// on the session. The analysis is already saved, so failures are only
+// logged.
+func publishReport(ctx context.Context, workerConfig *WorkerConfig, currentSession Session, results []AnalysesResult) {
+	if workerConfig.ReportStorage == "" {
+		return
+	}
+	report := buildSessionReport(currentSession, results, workerConfig, time.Now().UTC())
+	html, err := renderReportHTML(report)
+	if err != nil {
+		log.Printf("⚠️ Failed to render report for session %s: %v", currentSession.ID, err)
+		return
+	}
+	htmlKey, pdfKey := reportKey(currentSession.ID, "html"), reportKey(currentSession.ID, "pdf")
+	for _, upload := range []struct {
+		key, contentType string
+		data             []byte
+	}{
+		{htmlKey, "text/html; charset=utf-8", html},
+		{pdfKey, "application/pdf", renderReportPDF(report)},
+	} {
+		_, err := retry(3, func() (any, error) {
+			return nil, workerConfig.Storage.Put(ctx, workerConfig.ReportStorage, upload.key, upload.contentType, upload.data)
+		})
+		if err != nil {
+			log.Printf("⚠️ Failed to upload report %s: %v", upload.key, err)
+			return
+		}
+	}
+	_, err = retry(3, func() (any, error) {
+		return nil, workerConfig.DB.UpdateSessionReport(ctx, database.UpdateSessionReportParams{
+			ReportHtmlKey: htmlKey,
+			ReportPdfKey:  pdfKey,
+			ID:            currentSession.ID,
+		})
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to record report for session %s: %v", currentSession.ID, err)
+		return
+	}
+	log.Printf("📄 Report for session %s uploaded to %s", currentSession.ID, htmlKey)
+}
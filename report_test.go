@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReportConfig() *WorkerConfig {
+	return &WorkerConfig{ScoringMode: scoringMethodLLM, ScoreWeights: defaultScoreWeights()}
+}
+
+func TestBuildSessionReport(t *testing.T) {
+	results := []AnalysesResult{
+		{CandidateEmail: "low@example.com", MatchScore: 40},
+		{Filename: "failed.pdf", IsErrorResult: true, Error: "file is encrypted"},
+		{CandidateEmail: "high@example.com", MatchScore: 90, RelevantSkills: []string{"Go", " go ", ""}, RelevantExperiences: []string{"Backend lead"}},
+		{Filename: "tie.docx", MatchScore: 40, MissingSkills: []string{"Kubernetes"}, ScoreBreakdown: &ScoreBreakdown{MustHavesMissing: []string{"kubernetes", "Terraform"}}},
+		{CandidateEmail: "out@example.com", MatchScore: 95, KnockedOut: true, KnockoutReason: "requires 5 years of experience"},
+		{MatchScore: 70},
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	report := buildSessionReport(Session{JobTitle: "Backend Engineer"}, results, testReportConfig(), now)
+
+	type ranked struct {
+		Rank  int
+		Label string
+		Score int
+	}
+	var got []ranked
+	for _, c := range report.Ranked {
+		got = append(got, ranked{c.Rank, c.Label, c.Score})
+	}
+	// ties keep their input order; knocked out and failed files aren't ranked
+	want := []ranked{
+		{1, "high@example.com", 90},
+		{2, "Resume 6", 70},
+		{3, "low@example.com", 40},
+		{4, "tie.docx", 40},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Ranked = %v, want %v", got, want)
+	}
+
+	if strengths := report.Ranked[0].Strengths; !reflect.DeepEqual(strengths, []string{"Go", "Backend lead"}) {
+		t.Errorf("Strengths = %q, want [Go Backend lead]", strengths)
+	}
+	if gaps := report.Ranked[3].Gaps; !reflect.DeepEqual(gaps, []string{"Kubernetes", "Terraform"}) {
+		t.Errorf("Gaps = %q, want [Kubernetes Terraform]", gaps)
+	}
+
+	if len(report.KnockedOut) != 1 || report.KnockedOut[0].Label != "out@example.com" || report.KnockedOut[0].Reason != "requires 5 years of experience" {
+		t.Errorf("KnockedOut = %+v, want out@example.com with its knockout reason", report.KnockedOut)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Label != "failed.pdf" || report.Failed[0].Reason != "file is encrypted" {
+		t.Errorf("Failed = %+v, want failed.pdf with its error", report.Failed)
+	}
+
+	var percents []int
+	for _, weight := range report.Rubric {
+		percents = append(percents, weight.Percent)
+	}
+	if !reflect.DeepEqual(percents, []int{60, 20, 10, 10}) {
+		t.Errorf("Rubric percents = %v, want [60 20 10 10]", percents)
+	}
+	if !report.Generated.Equal(now) || report.Method != scoringMethodLLM {
+		t.Errorf("Generated, Method = %v, %q, want %v, %q", report.Generated, report.Method, now, scoringMethodLLM)
+	}
+}
+
+func TestBuildSessionReportRubricSkipsZeroWeights(t *testing.T) {
+	workerConfig := &WorkerConfig{ScoringMode: scoringMethodLexical, ScoreWeights: ScoreWeights{componentKeywords: 1}}
+	report := buildSessionReport(Session{}, nil, workerConfig, time.Now())
+	want := []reportWeight{{Name: rubricDescriptions[componentKeywords], Percent: 100}}
+	if !reflect.DeepEqual(report.Rubric, want) {
+		t.Errorf("Rubric = %+v, want %+v", report.Rubric, want)
+	}
+	if len(report.Ranked)+len(report.KnockedOut)+len(report.Failed) != 0 {
+		t.Errorf("report has candidates without results: %+v", report)
+	}
+}
+
+func TestRenderReportHTMLEscapesModelText(t *testing.T) {
+	results := []AnalysesResult{
+		{
+			CandidateEmail: "jane@example.com",
+			MatchScore:     80,
+			Summary:        `Strong fit <script>alert("x")</script>`,
+			Recomendation:  "Hire & <b>fast-track</b>",
+			RelevantSkills: []string{"<img src=x onerror=alert(1)>"},
+		},
+		{Filename: "<evil>.pdf", KnockedOut: true, KnockoutReason: "<i>no</i> work permit"},
+	}
+	report := buildSessionReport(Session{Name: "Q1 <hiring>", JobTitle: "Go & Rust"}, results, testReportConfig(), time.Now())
+	html, err := renderReportHTML(report)
+	if err != nil {
+		t.Fatalf("renderReportHTML: %v", err)
+	}
+	out := string(html)
+
+	for _, raw := range []string{"<script>", "<b>fast-track</b>", "<img src=x", "<evil>", "<i>no</i>", "Q1 <hiring>"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("report contains unescaped %q", raw)
+		}
+	}
+	for _, escaped := range []string{
+		"&lt;script&gt;",
+		"Hire &amp; &lt;b&gt;fast-track&lt;/b&gt;",
+		"&lt;img src=x onerror=alert(1)&gt;",
+		"&lt;evil&gt;.pdf: &lt;i&gt;no&lt;/i&gt; work permit",
+		"<h1>Go &amp; Rust</h1>",
+		"#1 jane@example.com",
+		"1 ranked, 1 knocked out, 0 not analysed",
+	} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("report is missing %q", escaped)
+		}
+	}
+}
+
+func TestRenderReportPDF(t *testing.T) {
+	results := []AnalysesResult{
+		{CandidateEmail: "jane@example.com", MatchScore: 80, Summary: "Strong Go background (8 years)."},
+		{Filename: "broken.pdf", IsErrorResult: true, Error: "failed to read pdf"},
+	}
+	report := buildSessionReport(Session{Name: "Spring hiring", JobTitle: "Backend Engineer"}, results, testReportConfig(), time.Now())
+	extraction, err := extractPDFPlainText(bytes.NewReader(renderReportPDF(report)))
+	if err != nil {
+		t.Fatalf("rendered report doesn't parse as a PDF: %v", err)
+	}
+	for _, want := range []string{"Backend Engineer", "#1 jane@example.com", "Strong Go background (8 years).", "broken.pdf: failed to read pdf"} {
+		if !strings.Contains(extraction.Text, want) {
+			t.Errorf("PDF text is missing %q:\n%s", want, extraction.Text)
+		}
+	}
+}
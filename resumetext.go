@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+// structuredExtractors map their format straight onto a profile, which the
+// stored text can't reproduce. They're cheap, so they always run again.
+var structuredExtractors = map[string]bool{"jsonresume": true, "europass": true}
+
+// loadResumeText returns the text stored for a resume by an earlier run, if
+// it was extracted from the same file (by SHA-256) by the current extractor
+// version.
+func loadResumeText(ctx context.Context, workerConfig *WorkerConfig, resume database.Resume, checksum string) *Extraction {
+	stored, err := workerConfig.DB.GetResumeText(ctx, resume.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to load stored text for %s: %v", resume.ObjectKey, err)
+		return nil
+	}
+	if stored.Checksum != checksum || stored.ExtractorVersion != extractorVersion || structuredExtractors[stored.Extractor] {
+		return nil
+	}
+	var extraction Extraction
+	if err := json.Unmarshal(stored.Extraction, &extraction); err != nil {
+		log.Printf("⚠️ Invalid stored extraction for %s: %v", resume.ObjectKey, err)
+		return nil
+	}
+	extraction.Text = stored.Text
+	log.Printf("♻️ Reusing text extracted from %s by %s", resume.ObjectKey, stored.Extractor)
+	return &extraction
+}
+
+// saveResumeText stores the text the analysis was given, for audit and for
+// later runs on the same file. Failures are logged, not returned.
+func saveResumeText(ctx context.Context, workerConfig *WorkerConfig, resume database.Resume, checksum string, extraction Extraction) {
+	if extraction.Text == "" {
+		return
+	}
+	warnings, err := json.Marshal(extraction.Warnings)
+	if err != nil {
+		log.Printf("⚠️ Failed to marshal extraction warnings for %s: %v", resume.ObjectKey, err)
+		return
+	}
+	// quality is assessed on every run
+	extraction.Quality = nil
+	details, err := json.Marshal(extraction)
+	if err != nil {
+		log.Printf("⚠️ Failed to marshal extraction for %s: %v", resume.ObjectKey, err)
+		return
+	}
+	_, err = retry(3, func() (any, error) {
+		return nil, workerConfig.DB.UpsertResumeText(ctx, database.UpsertResumeTextParams{
+			ResumeID:         resume.ID,
+			SessionID:        resume.SessionID,
+			Checksum:         checksum,
+			Text:             extraction.Text,
+			Extractor:        extraction.Extractor,
+			ExtractorVersion: extraction.Version,
+			Warnings:         warnings,
+			Extraction:       details,
+		})
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to save extracted text for %s: %v", resume.ObjectKey, err)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// rtfSkipDestinations are groups that hold formatting tables, metadata or
+// embedded binaries rather than document text.
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "objdata": true, "themedata": true,
+	"colorschememapping": true, "datastore": true, "latentstyles": true,
+	"listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "xmlnstbl": true, "mmathPr": true, "filetbl": true,
+	"revtbl": true, "pgdsctbl": true, "datafield": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// rtfSymbols maps control words that stand for a single character.
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n",
+	"tab": "\t", "cell": " | ", "nestcell": " | ",
+	"emdash": "—", "endash": "–", "bullet": "•", "emspace": " ", "enspace": " ",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+}
+
+// rtfState is the formatting state scoped to a group.
+type rtfState struct {
+	skip bool
+	// number of fallback characters following a \u escape
+	uc int
+	// inside a field, and inside its instruction (for hyperlink targets)
+	field   bool
+	fldinst bool
+}
+
+// extractRTFText returns the text of an RTF document. Formatting tables,
+// pictures and other non-text destinations are skipped, \' escapes are
+// decoded with the document's code page and \u escapes as Unicode.
+func extractRTFText(data []byte) (string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(string(data[:min(len(data), 16)])), `{\rtf`) {
+		return "", fmt.Errorf("failed to parse rtf: missing {\\rtf header")
+	}
+
+	var out, instr strings.Builder
+	var links []string
+	var pending []byte // \'hh bytes awaiting code page decoding
+	codePage := charmap.Windows1252.NewDecoder()
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		decoded, err := codePage.Bytes(pending)
+		if err != nil {
+			decoded = pending
+		}
+		out.Write(decoded)
+		pending = pending[:0]
+	}
+	write := func(s string, st rtfState) {
+		if st.fldinst {
+			instr.WriteString(s)
+			return
+		}
+		if st.skip {
+			return
+		}
+		flush()
+		out.WriteString(s)
+	}
+
+	state := rtfState{uc: 1}
+	var stack []rtfState
+	// characters still to drop after a \u escape
+	skipChars := 0
+	// whether the next control word opens a group ("{\*\dest" or "{\dest")
+	groupStart := false
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch c {
+		case '{':
+			stack = append(stack, state)
+			groupStart = true
+			skipChars = 0
+			i++
+			continue
+		case '}':
+			closed := state
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			if closed.fldinst && !state.fldinst {
+				if m := fieldHyperlinkArgs.FindStringSubmatch(instr.String()); m != nil {
+					links = append(links, m[1])
+				}
+				instr.Reset()
+			}
+			// a hyperlink field ends with its result; show the target after it
+			if closed.field && !state.field && len(links) > 0 {
+				flush()
+				for _, link := range links {
+					display := strings.TrimPrefix(link, "mailto:")
+					if !strings.Contains(lastLine(out.String()), display) {
+						out.WriteString(" (" + display + ")")
+					}
+				}
+				links = nil
+			}
+			groupStart = false
+			skipChars = 0
+			i++
+			continue
+		case '\r', '\n':
+			i++
+			continue
+		case '\\':
+		default:
+			groupStart = false
+			if skipChars > 0 {
+				skipChars--
+				i++
+				continue
+			}
+			// plain text runs up to the next special character
+			j := i
+			for j < len(data) && data[j] != '\\' && data[j] != '{' && data[j] != '}' && data[j] != '\r' && data[j] != '\n' {
+				j++
+			}
+			write(string(data[i:j]), state)
+			i = j
+			continue
+		}
+
+		// control symbol or control word
+		if i+1 >= len(data) {
+			break
+		}
+		next := data[i+1]
+		if !isASCIILetter(next) {
+			wasGroupStart := groupStart
+			groupStart = false
+			i += 2
+			switch next {
+			case '*':
+				// unknown destinations flagged with \* are ignorable; the
+				// destination word itself still follows
+				if wasGroupStart {
+					state.skip = true
+					groupStart = true
+				}
+			case '\'':
+				if i+2 <= len(data) {
+					if v, err := strconv.ParseUint(string(data[i:i+2]), 16, 8); err == nil {
+						if skipChars > 0 {
+							skipChars--
+						} else if !state.skip && !state.fldinst {
+							pending = append(pending, byte(v))
+						}
+					}
+					i += 2
+				}
+			case '~':
+				write(" ", state)
+			case '_':
+				write("-", state)
+			case '-':
+				// optional hyphen
+			case '\\', '{', '}':
+				write(string(next), state)
+			case '\r', '\n':
+				write("\n", state)
+			}
+			continue
+		}
+
+		j := i + 1
+		for j < len(data) && isASCIILetter(data[j]) {
+			j++
+		}
+		word := string(data[i+1 : j])
+		k := j
+		if k < len(data) && data[k] == '-' {
+			k++
+		}
+		for k < len(data) && data[k] >= '0' && data[k] <= '9' {
+			k++
+		}
+		param, hasParam := 0, k > j
+		if hasParam {
+			param, _ = strconv.Atoi(string(data[j:k]))
+		}
+		if k < len(data) && data[k] == ' ' {
+			k++
+		}
+		i = k
+
+		wasGroupStart := groupStart
+		groupStart = false
+		if skipChars > 0 {
+			skipChars--
+			continue
+		}
+
+		switch {
+		case word == "fldinst" && wasGroupStart:
+			state.fldinst = true
+			instr.Reset()
+		case word == "field" && wasGroupStart:
+			state.field = true
+		case (word == "listtext" || word == "pntext") && wasGroupStart:
+			// the rendered bullet or number of a list item
+			write("- ", state)
+			state.skip = true
+		case rtfSkipDestinations[word] && wasGroupStart:
+			state.skip = true
+		case word == "bin" && hasParam:
+			// raw binary data of the given length
+			i = min(i+max(param, 0), len(data))
+		case word == "ansicpg" && hasParam:
+			if dec := rtfCodePage(param); dec != nil {
+				codePage = dec
+			}
+		case word == "uc" && hasParam:
+			state.uc = max(param, 0)
+		case word == "u" && hasParam:
+			if param < 0 {
+				param += 65536
+			}
+			r := rune(param)
+			if !utf8.ValidRune(r) {
+				r = utf8.RuneError
+			}
+			write(string(r), state)
+			skipChars = state.uc
+		default:
+			if s, ok := rtfSymbols[word]; ok {
+				write(s, state)
+			}
+		}
+	}
+	flush()
+
+	lines := strings.Split(out.String(), "\n")
+	for n, line := range lines {
+		lines[n] = strings.TrimSuffix(strings.TrimRight(line, " \t"), " |")
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	return blankLinesPattern.ReplaceAllString(text, "\n\n"), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// rtfCodePage returns the decoder for a Windows code page number.
+func rtfCodePage(cp int) *encoding.Decoder {
+	pages := map[int]*charmap.Charmap{
+		437: charmap.CodePage437, 850: charmap.CodePage850, 852: charmap.CodePage852,
+		866: charmap.CodePage866, 874: charmap.Windows874,
+		1250: charmap.Windows1250, 1251: charmap.Windows1251, 1252: charmap.Windows1252,
+		1253: charmap.Windows1253, 1254: charmap.Windows1254, 1255: charmap.Windows1255,
+		1256: charmap.Windows1256, 1257: charmap.Windows1257, 1258: charmap.Windows1258,
+		10000: charmap.Macintosh,
+	}
+	if m, ok := pages[cp]; ok {
+		return m.NewDecoder()
+	}
+	return nil
+}
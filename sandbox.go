@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sandboxChildArg re-runs the binary as a sandbox child: the document
+// arrives on stdin and the operation's result is written to stdout as JSON.
+const sandboxChildArg = "extract-child"
+
+// Sandbox runs everything that parses untrusted bytes (sniffing, archive
+// and email expansion, extraction) in a child process, so a parser that
+// panics, spins or balloons on a malformed file takes down only that child.
+// Without a Path the operations run in-process, still with panic recovery
+// and the timeout.
+type Sandbox struct {
+	// Path is the worker binary, re-executed as the child.
+	Path    string
+	Timeout time.Duration
+	// MemoryLimit is the child's address space limit in bytes.
+	MemoryLimit int64
+	// builtin names the extractors the child knows; others, like OCR,
+	// always run in-process
+	builtin map[string]bool
+}
+
+func loadSandbox(enabled, timeout, memoryMB string) (*Sandbox, error) {
+	sandbox := &Sandbox{Timeout: 60 * time.Second, MemoryLimit: 1024 << 20, builtin: map[string]bool{}}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", timeout)
+		}
+		sandbox.Timeout = d
+	}
+	if memoryMB != "" {
+		n, err := strconv.ParseInt(memoryMB, 10, 64)
+		if err != nil || n < 64 {
+			return nil, fmt.Errorf("invalid memory limit %q, expected at least 64 (MB)", memoryMB)
+		}
+		sandbox.MemoryLimit = n << 20
+	}
+	if enabled == "false" {
+		return sandbox, nil
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("can't find the worker binary to re-execute: %w", err)
+	}
+	sandbox.Path = path
+	for _, e := range builtinExtractors() {
+		sandbox.builtin[e.Name()] = true
+	}
+	return sandbox, nil
+}
+
+// Operations the child runs. Each takes the document and one argument.
+const (
+	sandboxOpExtract = "extract"
+	sandboxOpSniff   = "sniff"
+	sandboxOpArchive = "archive"
+	sandboxOpEmail   = "email"
+)
+
+// sandboxOps are run by name in the child, or in-process without one.
+var sandboxOps = map[string]func(arg string, data []byte) (any, error){
+	sandboxOpExtract: func(name string, data []byte) (any, error) {
+		extractor, ok := NewExtractorRegistry(builtinExtractors()...).Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown extractor %q", name)
+		}
+		extraction, err := extractor.Extract(data)
+		return sandboxExtraction{Extraction: extraction, Text: extraction.Text, Profile: extraction.Profile}, err
+	},
+	sandboxOpSniff: func(declared string, data []byte) (any, error) {
+		mime, detected := resolveMime(declared, data)
+		return sniffResult{Mime: mime, Detected: detected}, nil
+	},
+	sandboxOpArchive: func(limits string, data []byte) (any, error) {
+		var archiveLimits ArchiveLimits
+		if err := json.Unmarshal([]byte(limits), &archiveLimits); err != nil {
+			return nil, fmt.Errorf("invalid archive limits: %w", err)
+		}
+		return expandArchive(data, archiveLimits)
+	},
+	sandboxOpEmail: func(mime string, data []byte) (any, error) {
+		return parseEmailMessage(mime, data)
+	},
+}
+
+// sandboxExtraction carries an Extraction across the process boundary. Text
+// and Profile are excluded from Extraction's JSON, so they travel
+// separately.
+type sandboxExtraction struct {
+	Extraction Extraction     `json:"extraction"`
+	Text       string         `json:"text"`
+	Profile    *ResumeProfile `json:"profile,omitempty"`
+}
+
+type sniffResult struct {
+	Mime     string `json:"mime"`
+	Detected string `json:"detected"`
+}
+
+// sandboxResult is what the child writes back.
+type sandboxResult struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Run extracts data with e, in the child process when possible.
+func (s *Sandbox) Run(e Extractor, data []byte) (Extraction, error) {
+	if s.inProcess() || !s.builtin[e.Name()] {
+		return extractWithTimeout(e, data, s.timeout())
+	}
+	result, err := sandboxCall[sandboxExtraction](s, sandboxOpExtract, e.Name(), data)
+	extraction := result.Extraction
+	extraction.Text = result.Text
+	extraction.Profile = result.Profile
+	return extraction, err
+}
+
+// ResolveMime sniffs data, see resolveMime.
+func (s *Sandbox) ResolveMime(declared string, data []byte) (mime, detected string, err error) {
+	result, err := sandboxCall[sniffResult](s, sandboxOpSniff, declared, data)
+	return result.Mime, result.Detected, err
+}
+
+// ExpandArchive reads the files out of a ZIP, see expandArchive.
+func (s *Sandbox) ExpandArchive(data []byte, limits ArchiveLimits) ([]archiveEntry, error) {
+	arg, err := json.Marshal(limits)
+	if err != nil {
+		return nil, err
+	}
+	return sandboxCall[[]archiveEntry](s, sandboxOpArchive, string(arg), data)
+}
+
+// ParseEmail parses an email or Outlook message, see parseEmailMessage.
+func (s *Sandbox) ParseEmail(mime string, data []byte) (*emailMessage, error) {
+	return sandboxCall[*emailMessage](s, sandboxOpEmail, mime, data)
+}
+
+// inProcess is true without a child binary; a nil Sandbox runs in-process
+// with the default timeout.
+func (s *Sandbox) inProcess() bool {
+	return s == nil || s.Path == ""
+}
+
+func (s *Sandbox) timeout() time.Duration {
+	if s == nil || s.Timeout <= 0 {
+		return 60 * time.Second
+	}
+	return s.Timeout
+}
+
+// sandboxCall runs op in the child process, or in-process without one.
+func sandboxCall[T any](s *Sandbox, op, arg string, data []byte) (T, error) {
+	var result T
+	if s.inProcess() {
+		data := bytes.Clone(data)
+		value, err := runWithTimeout(op, s.timeout(), func() (any, error) {
+			return sandboxOps[op](arg, data)
+		})
+		if value != nil {
+			result, _ = value.(T)
+		}
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.Path, sandboxChildArg, op, strconv.FormatInt(s.MemoryLimit, 10), arg)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// the child must not need the worker's configuration
+	cmd.Env = []string{"GOTRACEBACK=none"}
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("%s timed out after %s", op, s.timeout())
+	}
+	if err != nil {
+		reason, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		return result, fmt.Errorf("%s crashed: %v: %s", op, err, reason)
+	}
+	var out sandboxResult
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return result, fmt.Errorf("%s returned invalid output: %w", op, err)
+	}
+	if len(out.Result) > 0 {
+		if err := json.Unmarshal(out.Result, &result); err != nil {
+			return result, fmt.Errorf("%s returned invalid output: %w", op, err)
+		}
+	}
+	if out.Error != "" {
+		return result, errors.New(out.Error)
+	}
+	return result, nil
+}
+
+// runSandboxChild is the child side of sandboxCall.
+func runSandboxChild(args []string) {
+	log.SetFlags(0)
+	if len(args) != 3 {
+		log.Fatalf("usage: %s <operation> <memory limit> <argument>", sandboxChildArg)
+	}
+	if limit, err := strconv.ParseInt(args[1], 10, 64); err == nil && limit > 0 {
+		if err := limitMemory(limit); err != nil {
+			log.Fatalf("failed to limit memory: %v", err)
+		}
+	}
+	op, ok := sandboxOps[args[0]]
+	if !ok {
+		log.Fatalf("unknown operation %q", args[0])
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("failed to read document: %v", err)
+	}
+
+	value, err := safeCall(args[0], func() (any, error) { return op(args[2], data) })
+	var result sandboxResult
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if value != nil {
+		if result.Result, err = json.Marshal(value); err != nil {
+			log.Fatalf("failed to encode result: %v", err)
+		}
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		log.Fatalf("failed to write result: %v", err)
+	}
+}
+
+// safeCall turns a panic into an error.
+func safeCall(name string, fn func() (any, error)) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("💥 %s panicked: %v\n%s", name, r, debug.Stack())
+			value, err = nil, fmt.Errorf("%s panicked: %v", name, r)
+		}
+	}()
+	return fn()
+}
+
+// runWithTimeout runs fn in-process, giving up after timeout. A spinning
+// fn's goroutine can't be stopped and is left behind, which is why the
+// child process is preferred. The goroutine can outlive the caller's data,
+// e.g. a spooled download unmapped by Blob.Close, so callers hand fn a
+// copy.
+func runWithTimeout(name string, timeout time.Duration, fn func() (any, error)) (any, error) {
+	type outcome struct {
+		value any
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := safeCall(name, fn)
+		done <- outcome{value, err}
+	}()
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%s timed out after %s", name, timeout)
+	}
+}
+
+// extractWithTimeout runs an extractor in-process, giving up after timeout.
+func extractWithTimeout(e Extractor, data []byte, timeout time.Duration) (Extraction, error) {
+	data = bytes.Clone(data)
+	value, err := runWithTimeout("extractor "+e.Name(), timeout, func() (any, error) {
+		extraction, err := e.Extract(data)
+		return extraction, err
+	})
+	extraction, _ := value.(Extraction)
+	return extraction, err
+}
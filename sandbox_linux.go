@@ -0,0 +1,16 @@
+package main
+
+import (
+	"runtime/debug"
+	"syscall"
+)
+
+// limitMemory caps the process's writable memory, so runaway allocations
+// fail instead of growing until the host kills something. RLIMIT_DATA
+// rather than RLIMIT_AS: the Go runtime reserves far more address space
+// than it uses, and only committed (writable) mappings count towards it.
+// The Go heap target sits below the cap to let the GC try first.
+func limitMemory(limit int64) error {
+	debug.SetMemoryLimit(limit / 2)
+	return syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: uint64(limit), Max: uint64(limit)})
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import "runtime/debug"
+
+// limitMemory sets a soft limit only: address space limits are Linux
+// specific here.
+func limitMemory(limit int64) error {
+	debug.SetMemoryLimit(limit)
+	return nil
+}
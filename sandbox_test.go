@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+// An in-process extraction that times out keeps running. It must not be
+// reading a spooled download that Blob.Close has since unmapped.
+func TestAbandonedExtractionOutlivesBlob(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("resume "), 4096)
+	if err := os.WriteFile(filepath.Join(dir, "cv.txt"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	storage := NewBlobStores(DownloadLimits{MaxBytes: 1 << 20, SpoolBytes: 1024, TempDir: dir})
+	storage.Register(storageLocal, &localStore{root: dir})
+	blob, err := storage.Download(context.Background(), database.Resume{ObjectKey: "cv.txt", StorageProvider: storageLocal})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := make(chan int, 1)
+	slow := funcExtractor{name: "slow", extract: func(data []byte) (Extraction, error) {
+		time.Sleep(50 * time.Millisecond)
+		read <- bytes.Count(data, []byte("resume"))
+		return Extraction{}, nil
+	}}
+	if _, err := extractWithTimeout(slow, blob.Data, 10*time.Millisecond); err == nil {
+		t.Fatal("extractWithTimeout() succeeded, want a timeout")
+	}
+	if err := blob.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-read; got != 4096 {
+		t.Errorf("abandoned extractor read %d words, want 4096", got)
+	}
+}
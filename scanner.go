@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// errorCodeInfected marks results for files the scanner flagged.
+const errorCodeInfected = "malware_detected"
+
+// Scanner checks a downloaded file for malware before anything parses it.
+type Scanner interface {
+	// Scan returns the name of the detected threat, or "" for a clean file.
+	Scan(ctx context.Context, data []byte) (string, error)
+}
+
+// noopScanner is used when no scanner is configured.
+type noopScanner struct{}
+
+func (noopScanner) Scan(ctx context.Context, data []byte) (string, error) {
+	return "", nil
+}
+
+// clamdScanner streams files to a ClamAV daemon with the INSTREAM command.
+type clamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// clamdChunkSize is how much of the file goes in each INSTREAM chunk.
+const clamdChunkSize = 64 << 10
+
+// loadScanner returns a clamd client for address, or the no-op scanner when
+// address is empty. address is "unix:/path/to/clamd.sock", "tcp://host:port"
+// or plain "host:port".
+func loadScanner(address, timeout string) (Scanner, error) {
+	if address == "" {
+		return noopScanner{}, nil
+	}
+	scanner := &clamdScanner{network: "tcp", address: strings.TrimPrefix(address, "tcp://"), timeout: 2 * time.Minute}
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		scanner.network, scanner.address = "unix", path
+	}
+	if scanner.address == "" {
+		return nil, fmt.Errorf("invalid clamd address %q", address)
+	}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", timeout)
+		}
+		scanner.timeout = d
+	}
+	return scanner, nil
+}
+
+// Scan sends data as length-prefixed chunks ending with an empty chunk, then
+// reads clamd's single reply: "stream: OK", "stream: <threat> FOUND" or
+// "<reason> ERROR".
+func (s *clamdScanner) Scan(ctx context.Context, data []byte) (string, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	var size [4]byte
+	for len(data) > 0 {
+		chunk := data[:min(len(data), clamdChunkSize)]
+		data = data[len(chunk):]
+		binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+		w.Write(size[:])
+		w.Write(chunk)
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	w.Write(size[:])
+	if err := w.Flush(); err != nil {
+		// clamd closes the connection once a stream passes its
+		// StreamMaxLength; its reply says so
+		if reply, readErr := readClamdReply(conn); readErr == nil {
+			return "", fmt.Errorf("clamd: %s", reply)
+		}
+		return "", fmt.Errorf("failed to send file to clamd: %w", err)
+	}
+
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// readClamdReply reads a NUL terminated reply to a z-prefixed command.
+func readClamdReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+// clamdStub answers every INSTREAM request with reply, recording the chunk
+// sizes and the data it received.
+type clamdStub struct {
+	addr   string
+	chunks chan []int
+	data   chan []byte
+}
+
+func startClamdStub(t *testing.T, reply string) *clamdStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	stub := &clamdStub{addr: ln.Addr().String(), chunks: make(chan []int, 1), data: make(chan []byte, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			stub.serve(conn, reply)
+		}
+	}()
+	return stub
+}
+
+func (s *clamdStub) serve(conn net.Conn, reply string) {
+	defer conn.Close()
+	cmd := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var sizes []int
+	var data bytes.Buffer
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n == 0 {
+			break
+		}
+		sizes = append(sizes, int(n))
+		if _, err := io.CopyN(&data, conn, int64(n)); err != nil {
+			return
+		}
+	}
+	s.chunks <- sizes
+	s.data <- data.Bytes()
+	conn.Write([]byte(reply + "\x00"))
+}
+
+func TestClamdScannerScan(t *testing.T) {
+	file := bytes.Repeat([]byte("resume "), (2*clamdChunkSize+10)/7+1)[:2*clamdChunkSize+10]
+
+	tests := []struct {
+		name       string
+		reply      string
+		wantThreat string
+		wantErr    string
+	}{
+		{"clean", "stream: OK", "", ""},
+		{"infected", "stream: Eicar-Test-Signature FOUND", "Eicar-Test-Signature", ""},
+		{"error", "INSTREAM size limit exceeded. ERROR", "", "clamd: INSTREAM size limit exceeded. ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := startClamdStub(t, tt.reply)
+			scanner, err := loadScanner("tcp://"+stub.addr, "5s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			threat, err := scanner.Scan(context.Background(), file)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Scan error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if threat != tt.wantThreat {
+				t.Errorf("Scan threat = %q, want %q", threat, tt.wantThreat)
+			}
+
+			sizes := <-stub.chunks
+			if want := []int{clamdChunkSize, clamdChunkSize, 10}; !slices.Equal(sizes, want) {
+				t.Errorf("chunk sizes = %v, want %v", sizes, want)
+			}
+			if got := <-stub.data; !bytes.Equal(got, file) {
+				t.Errorf("clamd received %d bytes that don't match the %d byte file", len(got), len(file))
+			}
+		})
+	}
+}
+
+func TestClamdScannerUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	scanner, err := loadScanner(addr, "1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.Scan(context.Background(), []byte("resume")); err == nil || !strings.Contains(err.Error(), "failed to connect to clamd") {
+		t.Errorf("Scan error = %v, want a connection error", err)
+	}
+}
+
+func TestQuarantineResume(t *testing.T) {
+	root := t.TempDir()
+	key := "uploads/session/cv.pdf"
+	if err := os.MkdirAll(filepath.Join(root, "uploads", "session"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(key)), []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := NewBlobStores(DownloadLimits{MaxBytes: 1 << 20, SpoolBytes: 1 << 20})
+	storage.Register(storageLocal, local)
+	storage.Register(storageHTTP, NewHTTPStore(time.Second, []string{"files.example.com"}))
+	workerConfig := &WorkerConfig{Storage: storage}
+
+	t.Run("moved", func(t *testing.T) {
+		resume := database.Resume{ObjectKey: key, StorageProvider: storageLocal}
+		result := quarantineResume(context.Background(), workerConfig, resume, "Eicar-Test-Signature")
+		if !result.IsErrorResult || result.ErrorCode != errorCodeInfected || !strings.Contains(result.Error, "Eicar-Test-Signature") {
+			t.Errorf("result = %+v, want an %s error naming the threat", result, errorCodeInfected)
+		}
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(key))); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("file is still in the upload area: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(quarantinePrefix+key))); err != nil {
+			t.Errorf("file isn't in quarantine: %v", err)
+		}
+	})
+
+	// a store that can't quarantine still gets the file rejected
+	t.Run("unsupported store", func(t *testing.T) {
+		resume := database.Resume{StorageUrl: "https://files.example.com/cv.pdf", StorageProvider: storageHTTP}
+		result := quarantineResume(context.Background(), workerConfig, resume, "Eicar-Test-Signature")
+		if !result.IsErrorResult || result.ErrorCode != errorCodeInfected {
+			t.Errorf("result = %+v, want an %s error", result, errorCodeInfected)
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	scoringMethodLLM     = "llm"
+	scoringMethodLexical = "lexical"
+
+	// term-frequency saturation and length normalisation parameters, as in
+	// BM25's TF component. There is no IDF: the terms come from a single job
+	// description and jdTerms already weights them.
+	tfK1 = 1.2
+	tfB  = 0.75
+	// average resume length in tokens, used for length normalisation
+	avgResumeTokens = 500.0
+	// only the highest-weighted JD terms are scored so long boilerplate JDs
+	// don't drown out the actual requirements
+	maxJDTerms = 40
+	// how many matched/missing terms are reported back
+	maxReportedTerms = 20
+)
+
+var (
+	tokenPattern = regexp.MustCompile(`[a-z0-9][a-z0-9_+#.\-]*`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// phraseSynonyms are rewritten before tokenizing so multi-word skills and
+// spellings with punctuation survive as a single term. They only match whole
+// tokens: "interest api" and "asp.net" are left alone.
+var phraseSynonyms = []struct{ from, to string }{
+	{"machine learning", "machine_learning"},
+	{"deep learning", "deep_learning"},
+	{"natural language processing", "nlp"},
+	{"computer vision", "computer_vision"},
+	{"data science", "data_science"},
+	{"data engineering", "data_engineering"},
+	{"project management", "project_management"},
+	{"product management", "product_management"},
+	{"google cloud platform", "gcp"},
+	{"google cloud", "gcp"},
+	{"amazon web services", "aws"},
+	{"microsoft azure", "azure"},
+	{"ci/cd", "cicd"},
+	{"ci-cd", "cicd"},
+	{"continuous integration", "cicd"},
+	{"ruby on rails", "rails"},
+	{"react native", "react_native"},
+	{"spring boot", "spring_boot"},
+	{"unit testing", "unit_testing"},
+	{"rest api", "rest"},
+	{"restful", "rest"},
+	{".net", "dotnet"},
+	{"objective-c", "objective_c"},
+	{"power bi", "power_bi"},
+}
+
+// skillSynonyms folds common spellings and abbreviations of a skill onto one
+// canonical term.
+var skillSynonyms = map[string]string{
+	"golang":       "go",
+	"js":           "javascript",
+	"ecmascript":   "javascript",
+	"es6":          "javascript",
+	"ts":           "typescript",
+	"py":           "python",
+	"python3":      "python",
+	"k8s":          "kubernetes",
+	"postgres":     "postgresql",
+	"psql":         "postgresql",
+	"mongo":        "mongodb",
+	"node":         "nodejs",
+	"node.js":      "nodejs",
+	"reactjs":      "react",
+	"react.js":     "react",
+	"vuejs":        "vue",
+	"vue.js":       "vue",
+	"angularjs":    "angular",
+	"nextjs":       "next.js",
+	"ml":           "machine_learning",
+	"dl":           "deep_learning",
+	"sklearn":      "scikit-learn",
+	"tf":           "tensorflow",
+	"c-sharp":      "c#",
+	"csharp":       "c#",
+	"cpp":          "c++",
+	"front-end":    "frontend",
+	"back-end":     "backend",
+	"full-stack":   "fullstack",
+	"ec2":          "aws",
+	"s3":           "aws",
+	"rabbit":       "rabbitmq",
+	"elastic":      "elasticsearch",
+	"gitlab-ci":    "cicd",
+	"mssql":        "sql_server",
+	"ms-sql":       "sql_server",
+	"tailwindcss":  "tailwind",
+	"springboot":   "spring_boot",
+	"scrum-master": "scrum",
+}
+
+// knownSkills get double weight when scoring and are the only terms reported
+// as relevant/missing skills.
+var knownSkills = map[string]bool{}
+
+func init() {
+	for _, canonical := range skillSynonyms {
+		knownSkills[canonical] = true
+	}
+	for _, p := range phraseSynonyms {
+		knownSkills[p.to] = true
+	}
+	for _, s := range []string{
+		"go", "python", "java", "javascript", "typescript", "ruby", "php", "rust", "scala", "kotlin",
+		"swift", "c", "c++", "c#", "r", "sql", "nosql", "html", "css", "sass", "graphql", "grpc",
+		"docker", "kubernetes", "terraform", "ansible", "linux", "bash", "git", "github", "gitlab",
+		"aws", "azure", "gcp", "redis", "kafka", "rabbitmq", "postgresql", "mysql", "mongodb",
+		"elasticsearch", "dynamodb", "cassandra", "sqlite", "react", "vue", "angular", "svelte",
+		"django", "flask", "fastapi", "spring", "laravel", "express", "pandas", "numpy", "pytorch",
+		"tensorflow", "keras", "spark", "hadoop", "airflow", "dbt", "snowflake", "tableau", "excel",
+		"figma", "jira", "microservices", "serverless", "security", "devops", "sre", "nginx",
+		"prometheus", "grafana", "selenium", "cypress", "jest", "pytest", "android", "ios", "flutter",
+		"llm", "openai", "langchain", "etl", "api", "oauth", "jwt", "tdd", "kanban", "mlops",
+		"jenkins", "agile", "scrum", "rest", "nlp",
+	} {
+		knownSkills[s] = true
+	}
+}
+
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a about above across after again against all also am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for
+		from further had has have having he her here hers herself him himself his how i if in into is
+		it its itself just me more most my myself no nor not now of off on once only or other our
+		ours ourselves out over own same she should so some such than that the their theirs them
+		themselves then there these they this those through to too under until up very was we were
+		what when where which while who whom why will with would you your yours yourself yourselves
+		etc e.g i.e via per without include includes using use used
+		experience experienced years year work working worked team teams ability able strong
+		excellent good great knowledge understanding skills skill role position job candidate
+		candidates looking join company responsibilities responsibility requirements required
+		requirement preferred plus must nice have bonus ideal ideally new well based across
+		opportunity environment help support ensure provide develop developing development
+		building build day days make making one two three four five six seven
+		eight nine ten least minimum related relevant field equivalent offer benefits
+		need needs seeking want wants`) {
+		stopwords[w] = true
+	}
+}
+
+// tokenize lowercases text, folds phrase and skill synonyms and returns the
+// remaining content terms in order.
+func tokenize(text string) []string {
+	lower := " " + strings.ToLower(text) + " "
+	for _, p := range phraseSynonyms {
+		lower = replacePhrase(lower, p.from, p.to)
+	}
+
+	var tokens []string
+	for _, raw := range tokenPattern.FindAllString(lower, -1) {
+		tok := strings.TrimRight(raw, ".-")
+		if canonical, ok := skillSynonyms[tok]; ok {
+			tok = canonical
+		}
+		if tok == "" || stopwords[tok] {
+			continue
+		}
+		if len(tok) < 2 && !knownSkills[tok] {
+			continue
+		}
+		if isNumeric(tok) {
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// replacePhrase replaces the occurrences of phrase in s that start and end
+// on token boundaries, as tokenPattern splits them.
+func replacePhrase(s, phrase, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, phrase)
+		if i < 0 {
+			break
+		}
+		end := i + len(phrase)
+		if (i == 0 || !isTokenByte(s[i-1])) && tokenEndsAt(s, end) {
+			b.WriteString(s[:i])
+			b.WriteString(" " + replacement + " ")
+			s = s[end:]
+			continue
+		}
+		// phrases start with an ASCII byte, so this doesn't split a rune
+		b.WriteString(s[:i+1])
+		s = s[i+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func isTokenByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("_+#.-", c) >= 0
+}
+
+// tokenEndsAt reports whether the token running up to i ends there, once
+// tokenize has trimmed its trailing dots and dashes: "machine learning."
+// ends after the "g", ".netcore" doesn't end after the "t".
+func tokenEndsAt(s string, i int) bool {
+	for ; i < len(s) && (s[i] == '.' || s[i] == '-'); i++ {
+	}
+	return i == len(s) || !isTokenByte(s[i])
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' && r != '+' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func termCounts(tokens []string) map[string]int {
+	counts := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		counts[t]++
+	}
+	return counts
+}
+
+type weightedTerm struct {
+	term   string
+	weight float64
+}
+
+// jdTerms returns the highest-weighted terms of the job. Title terms count
+// three times, and known skills are weighted double.
+func jdTerms(jobTitle, jobDescription string) []weightedTerm {
+	counts := termCounts(tokenize(jobDescription))
+	for _, t := range tokenize(jobTitle) {
+		counts[t] += 3
+	}
+
+	terms := make([]weightedTerm, 0, len(counts))
+	for term, n := range counts {
+		w := 1 + math.Log(float64(n))
+		if knownSkills[term] {
+			w *= 2
+		}
+		terms = append(terms, weightedTerm{term: term, weight: w})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].weight != terms[j].weight {
+			return terms[i].weight > terms[j].weight
+		}
+		return terms[i].term < terms[j].term
+	})
+	if len(terms) > maxJDTerms {
+		terms = terms[:maxJDTerms]
+	}
+	return terms
+}
+
+// saturatedTF dampens repeated mentions of a term and discounts mentions in
+// longer resumes.
+func saturatedTF(tf, docLen float64) float64 {
+	norm := 1 - tfB + tfB*docLen/avgResumeTokens
+	return tf * (tfK1 + 1) / (tf + tfK1*norm)
+}
+
+type lexicalMatch struct {
+	Score   int
+	Matched []string
+	Missing []string
+	// Coverage is the weighted share of JD terms present at least once.
+	Coverage float64
+}
+
+// matchTerms scores resumeText against the JD terms. A term mentioned twice
+// earns full credit; a single mention earns partial credit that shrinks with
+// resume length, so a long resume that mentions everything once doesn't win
+// by volume.
+func matchTerms(terms []weightedTerm, resumeText string) lexicalMatch {
+	resumeTokens := tokenize(resumeText)
+	counts := termCounts(resumeTokens)
+	docLen := float64(len(resumeTokens))
+
+	var m lexicalMatch
+	var total, earned, covered float64
+	for _, t := range terms {
+		total += t.weight
+		tf := float64(counts[t.term])
+		if tf == 0 {
+			m.Missing = append(m.Missing, displayTerm(t.term))
+			continue
+		}
+		covered += t.weight
+		earned += t.weight * math.Min(1, saturatedTF(tf, docLen)/saturatedTF(2, docLen))
+		m.Matched = append(m.Matched, displayTerm(t.term))
+	}
+	if total > 0 {
+		m.Score = int(math.Round(100 * earned / total))
+		m.Coverage = covered / total
+	}
+	return m
+}
+
+func displayTerm(term string) string {
+	return strings.ReplaceAll(term, "_", " ")
+}
+
+// lexicalScore ranks a resume against a job without calling the model. It
+// produces the same result shape as the agent, marked with the lexical
+// method, so it can stand in when the agent is unavailable.
+func lexicalScore(jobTitle, jobDescription, resumeText string) AnalysesResult {
+	terms := jdTerms(jobTitle, jobDescription)
+	m := matchTerms(terms, resumeText)
+
+	result := AnalysesResult{
+		CandidateEmail: emailPattern.FindString(resumeText),
+		MatchScore:     m.Score,
+		Method:         scoringMethodLexical,
+		MatchedTerms:   capTerms(m.Matched),
+		MissingTerms:   capTerms(m.Missing),
+	}
+	for _, t := range m.Matched {
+		if knownSkills[strings.ReplaceAll(t, " ", "_")] {
+			result.RelevantSkills = append(result.RelevantSkills, t)
+		}
+	}
+	for _, t := range m.Missing {
+		if knownSkills[strings.ReplaceAll(t, " ", "_")] {
+			result.MissingSkills = append(result.MissingSkills, t)
+		}
+	}
+	result.Summary = fmt.Sprintf("Keyword match: %d of %d job description terms found in the resume (%d relevant skills, %d missing).",
+		len(m.Matched), len(terms), len(result.RelevantSkills), len(result.MissingSkills))
+	result.Recomendation = lexicalRecommendation(m.Score)
+	return result
+}
+
+// lexicalFallback replaces a failed model result with a lexical score,
+// recording why the model result couldn't be used.
+func lexicalFallback(currentSession Session, resumeText string, failed AnalysesResult) AnalysesResult {
+	result := lexicalScore(currentSession.JobTitle, currentSession.JobDescription, resumeText)
+	result.FallbackReason = failed.Error
+	return result
+}
+
+func lexicalRecommendation(score int) string {
+	switch {
+	case score >= 75:
+		return "Strong keyword match. Recommend for interview."
+	case score >= 50:
+		return "Partial keyword match. Review manually."
+	default:
+		return "Weak keyword match. Not recommended without further review."
+	}
+}
+
+func capTerms(terms []string) []string {
+	if len(terms) > maxReportedTerms {
+		return terms[:maxReportedTerms]
+	}
+	return terms
+}
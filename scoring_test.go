@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenizePhraseSynonyms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Machine learning and deep learning.", []string{"machine_learning", "deep_learning"}},
+		{"machine learning machine learning", []string{"machine_learning", "machine_learning"}},
+		{"Built a REST API", []string{"built", "rest"}},
+		{"Showed interest api design", []string{"showed", "interest", "api", "design"}},
+		{"C#/.NET developer", []string{"c#", "dotnet", "developer"}},
+		{"ASP.NET MVC", []string{"asp.net", "mvc"}},
+		{".netcore services", []string{"netcore", "services"}},
+		{"CI/CD pipelines", []string{"cicd", "pipelines"}},
+		{"Restful services", []string{"rest", "services"}},
+		{"Restfulness", []string{"restfulness"}},
+		{"Google Cloud Platform (GCP)", []string{"gcp", "gcp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTerms(t *testing.T) {
+	terms := []weightedTerm{{"go", 2}, {"docker", 1}, {"kafka", 1}}
+	tests := []struct {
+		name     string
+		resume   string
+		score    int
+		matched  []string
+		missing  []string
+		coverage float64
+	}{
+		// go twice earns full credit, docker once earns partial credit
+		{"short resume", "Go, Go and Docker", 72, []string{"go", "docker"}, []string{"kafka"}, 0.75},
+		{"every term twice", "go docker kafka go docker kafka", 100, []string{"go", "docker", "kafka"}, nil, 1},
+		{"nothing matched", "Java and Spring", 0, nil, []string{"go", "docker", "kafka"}, 0},
+		{"every term once", "go docker kafka", 88, []string{"go", "docker", "kafka"}, nil, 1},
+		// the same single mentions count for less among 600 filler tokens
+		{"every term once in a long resume", "go docker kafka " + strings.Repeat("filler ", 600), 71, []string{"go", "docker", "kafka"}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := matchTerms(terms, tt.resume)
+			if m.Score != tt.score {
+				t.Errorf("Score = %d, want %d", m.Score, tt.score)
+			}
+			if !slices.Equal(m.Matched, tt.matched) || !slices.Equal(m.Missing, tt.missing) {
+				t.Errorf("Matched, Missing = %q, %q, want %q, %q", m.Matched, m.Missing, tt.matched, tt.missing)
+			}
+			if math.Abs(m.Coverage-tt.coverage) > 1e-9 {
+				t.Errorf("Coverage = %v, want %v", m.Coverage, tt.coverage)
+			}
+		})
+	}
+	if m := matchTerms(nil, "go"); m.Score != 0 || m.Coverage != 0 {
+		t.Errorf("matchTerms with no terms = %+v, want zero", m)
+	}
+}
+
+func TestLexicalScore(t *testing.T) {
+	jobTitle := "Go Engineer"
+	jobDescription := "We need Go and Kubernetes. PostgreSQL is a plus."
+	resume := "jane@example.com\nGo engineer. Built Go services on Kubernetes and Kubernetes operators in Go."
+
+	result := lexicalScore(jobTitle, jobDescription, resume)
+	if result.Method != scoringMethodLexical {
+		t.Errorf("Method = %q, want %q", result.Method, scoringMethodLexical)
+	}
+	if result.CandidateEmail != "jane@example.com" {
+		t.Errorf("CandidateEmail = %q", result.CandidateEmail)
+	}
+	if result.MatchScore != 79 {
+		t.Errorf("MatchScore = %d, want 79", result.MatchScore)
+	}
+	// ordered by JD weight: title terms count triple, skills double
+	if want := []string{"go", "engineer", "kubernetes"}; !slices.Equal(result.MatchedTerms, want) {
+		t.Errorf("MatchedTerms = %q, want %q", result.MatchedTerms, want)
+	}
+	if want := []string{"postgresql"}; !slices.Equal(result.MissingSkills, want) {
+		t.Errorf("MissingSkills = %q, want %q", result.MissingSkills, want)
+	}
+	if result.Recomendation != lexicalRecommendation(79) {
+		t.Errorf("Recomendation = %q", result.Recomendation)
+	}
+}
+
+func TestLexicalFallback(t *testing.T) {
+	currentSession := Session{JobTitle: "Go Engineer", JobDescription: "Go and Kubernetes"}
+	failed := buildResult("", true, "agent stream error: timeout")
+
+	result := lexicalFallback(currentSession, "Go developer", failed)
+	if result.Method != scoringMethodLexical {
+		t.Errorf("Method = %q, want %q", result.Method, scoringMethodLexical)
+	}
+	if result.IsErrorResult {
+		t.Error("fallback result is still an error result")
+	}
+	if result.FallbackReason != failed.Error {
+		t.Errorf("FallbackReason = %q, want %q", result.FallbackReason, failed.Error)
+	}
+	if want := lexicalScore(currentSession.JobTitle, currentSession.JobDescription, "Go developer").MatchScore; result.MatchScore != want {
+		t.Errorf("MatchScore = %d, want %d", result.MatchScore, want)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	mimeText     = "text/plain"
+	mimePDF      = "application/pdf"
+	mimeDocx     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeDoc      = "application/msword"
+	mimeODT      = "application/vnd.oasis.opendocument.text"
+	mimeRTF      = "application/rtf"
+	mimeHTML     = "text/html"
+	mimeMarkdown = "text/markdown"
+	mimeJSON     = "application/json"
+	mimeXML      = "application/xml"
+	mimeEmail    = "message/rfc822"
+	mimeMsg      = "application/vnd.ms-outlook"
+	mimePNG      = "image/png"
+	mimeJPEG     = "image/jpeg"
+	mimeTIFF     = "image/tiff"
+	mimeZip      = "application/zip"
+	mimeOLE      = "application/x-ole-storage"
+	mimeOctet    = "application/octet-stream"
+	sniffWindow  = 1024
+	maxSniffZipN = 10000
+)
+
+var (
+	pdfMagic = []byte("%PDF-")
+	zipMagic = []byte("PK\x03\x04")
+	oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	rtfMagic = []byte(`{\rtf`)
+	utf8BOM  = []byte{0xEF, 0xBB, 0xBF}
+
+	pngMagic    = []byte("\x89PNG\r\n\x1a\n")
+	jpegMagic   = []byte{0xFF, 0xD8, 0xFF}
+	tiffMagicLE = []byte("II*\x00")
+	tiffMagicBE = []byte("MM\x00*")
+)
+
+// mimeAliases maps non-standard MIME types browsers and uploaders send to
+// the canonical type.
+var mimeAliases = map[string]string{
+	"application/x-pdf":                         mimePDF,
+	"application/acrobat":                       mimePDF,
+	"text/pdf":                                  mimePDF,
+	"text/rtf":                                  mimeRTF,
+	"application/x-rtf":                         mimeRTF,
+	"application/vnd.ms-word":                   mimeDoc,
+	"application/x-zip":                         mimeZip,
+	"application/x-zip-compressed":              mimeZip,
+	"application/xhtml+xml":                     mimeHTML,
+	"application/x-msword":                      mimeDoc,
+	"application/doc":                           mimeDoc,
+	"application/x-vnd.oasis.opendocument.text": mimeODT,
+	"text/x-markdown":                           mimeMarkdown,
+	"text/json":                                 mimeJSON,
+	"application/x-json":                        mimeJSON,
+	"text/xml":                                  mimeXML,
+	"application/x-outlook-msg":                 mimeMsg,
+	"application/eml":                           mimeEmail,
+}
+
+// normalizeMime lowercases a declared MIME type, drops its parameters and
+// resolves common aliases.
+func normalizeMime(declared string) string {
+	mt, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.SplitN(declared, ";", 2)[0]))
+	}
+	if alias, ok := mimeAliases[mt]; ok {
+		return alias
+	}
+	return mt
+}
+
+// sniffMime detects a file's type from its content: magic bytes for PDF,
+// ZIP, OLE2, RTF and images, the container layout for ZIP and OLE2 based
+// formats, and markup or text encoding for HTML, JSON, XML, email and plain
+// text. It returns application/octet-stream when nothing matches.
+func sniffMime(data []byte) string {
+	head := data
+	if len(head) > sniffWindow {
+		head = head[:sniffWindow]
+	}
+
+	// prefix magics come first: a ZIP, OLE2 or image file can carry the
+	// PDF header in its first 1KB (an embedded or stored PDF, metadata)
+	switch {
+	case bytes.HasPrefix(data, pdfMagic):
+		return mimePDF
+	case bytes.HasPrefix(data, zipMagic):
+		return sniffZip(data)
+	case bytes.HasPrefix(data, oleMagic):
+		return sniffOLE(data)
+	case bytes.HasPrefix(data, pngMagic):
+		return mimePNG
+	case bytes.HasPrefix(data, jpegMagic):
+		return mimeJPEG
+	case bytes.HasPrefix(data, tiffMagicLE), bytes.HasPrefix(data, tiffMagicBE):
+		return mimeTIFF
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, utf8BOM), " \t\r\n")
+	if bytes.HasPrefix(trimmed, rtfMagic) {
+		return mimeRTF
+	}
+	if isHTML(trimmed) {
+		return mimeHTML
+	}
+	// structured resumes from job boards
+	if bytes.HasPrefix(trimmed, []byte("{")) && json.Valid(bytes.TrimPrefix(data, utf8BOM)) {
+		return mimeJSON
+	}
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<SkillsPassport")) {
+		return mimeXML
+	}
+	// the PDF header may be preceded by junk; readers accept it within the
+	// first 1KB
+	if bytes.Contains(head, pdfMagic) {
+		return mimePDF
+	}
+	if looksLikeEmail(trimmed) {
+		return mimeEmail
+	}
+	if looksLikeText(head) {
+		return mimeText
+	}
+	return mimeOctet
+}
+
+// sniffZip tells DOCX and ODT apart from a plain ZIP archive by their
+// container structure.
+func sniffZip(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return mimeZip
+	}
+	for i, f := range zr.File {
+		if i >= maxSniffZipN {
+			break
+		}
+		switch f.Name {
+		case "word/document.xml":
+			return mimeDocx
+		case "mimetype":
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			declared, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			if strings.TrimSpace(string(declared)) == mimeODT {
+				return mimeODT
+			}
+		}
+	}
+	return mimeZip
+}
+
+// sniffOLE recognises Word documents and Outlook messages among OLE2
+// compound files.
+func sniffOLE(data []byte) string {
+	cfb, err := openCFB(data)
+	if err != nil {
+		return mimeOLE
+	}
+	if id, ok := cfb.find(0, "WordDocument"); ok && cfb.entries[id].typ == cfbTypeStream {
+		return mimeDoc
+	}
+	if isOutlookMsg(cfb) {
+		return mimeMsg
+	}
+	return mimeOLE
+}
+
+func isHTML(trimmed []byte) bool {
+	lower := strings.ToLower(string(trimmed))
+	for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(http.DetectContentType(trimmed), mimeHTML)
+}
+
+// looksLikeText reports whether data is valid UTF-8 (a multi-byte sequence
+// cut off at the window edge is fine) without binary control bytes.
+func looksLikeText(head []byte) bool {
+	if len(head) == 0 {
+		return true
+	}
+	for len(head) > 0 {
+		r, size := utf8.DecodeRune(head)
+		if r == utf8.RuneError && size <= 1 {
+			// allow a truncated rune at the very end of the window
+			return len(head) < utf8.UTFMax && !utf8.FullRune(head)
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' {
+			return false
+		}
+		head = head[size:]
+	}
+	return true
+}
+
+// resolveMime picks the type to extract with. The sniffed type wins over
+// whatever the uploader declared, unless sniffing only got as far as "some
+// binary" or "an OLE2 container", or found plain text where a more specific
+// text format was declared.
+func resolveMime(declared string, data []byte) (effective, detected string) {
+	declared = normalizeMime(declared)
+	detected = sniffMime(data)
+	switch detected {
+	case mimeOctet, mimeOLE:
+		if declared != "" && declared != mimeOctet {
+			return declared, detected
+		}
+	case mimeText:
+		// plain text can't be told apart from formats we don't sniff
+		// (markdown, JSON, ...), so a declared text type is kept
+		if declared != "" && declared != mimeOctet && !isBinaryMime(declared) {
+			return declared, detected
+		}
+	}
+	return detected, detected
+}
+
+// isBinaryMime reports whether a declared type promises binary content,
+// which is a mismatch when the file turned out to be plain text.
+func isBinaryMime(mt string) bool {
+	switch mt {
+	case mimePDF, mimeDocx, mimeDoc, mimeODT, mimeZip, mimeOLE, mimeMsg:
+		return true
+	}
+	return strings.HasPrefix(mt, "image/")
+}
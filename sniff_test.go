@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipWith builds a ZIP holding the named files, stored uncompressed so
+// their content shows up in the first bytes.
+func zipWith(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniffMime(t *testing.T) {
+	sample := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata", "samples", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	pdfFixture, err := os.ReadFile(filepath.Join("testdata", "pdf", "two-columns.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"pdf", pdfFixture, mimePDF},
+		{"pdf after junk", append([]byte("\x00\x01garbage\r\n"), pdfFixture...), mimePDF},
+		{"zip holding a stored pdf", zipWith(t, map[string]string{"cv.pdf": "%PDF-1.4 ..."}), mimeZip},
+		{"docx holding a stored pdf", zipWith(t, map[string]string{"word/document.xml": "<w:document/>", "word/media/cv.pdf": "%PDF-1.7"}), mimeDocx},
+		{"odt", sample("resume.odt"), mimeODT},
+		{"doc", sample("resume.doc"), mimeDoc},
+		{"doc mentioning a pdf", append(bytes.Clone(sample("resume.doc")), "%PDF-1.4"...), mimeDoc},
+		{"bare ole2", append(bytes.Clone(oleMagic), make([]byte, 600)...), mimeOLE},
+		{"png with pdf metadata", append(bytes.Clone(pngMagic), "tEXtSource%PDF-1.4"...), mimePNG},
+		{"jpeg with pdf metadata", append(bytes.Clone(jpegMagic), "\xe0Exif%PDF-1.4"...), mimeJPEG},
+		{"tiff", append(bytes.Clone(tiffMagicLE), "%PDF-"...), mimeTIFF},
+		{"rtf", sample("resume.rtf"), mimeRTF},
+		{"html", sample("resume.html"), mimeHTML},
+		{"json", []byte(`{"basics": {"name": "Jane Doe"}}`), mimeJSON},
+		{"xml", []byte(`<?xml version="1.0"?><SkillsPassport/>`), mimeXML},
+		{"email", []byte("From: jane@example.com\r\nTo: jobs@example.com\r\nSubject: Application\r\n\r\nHello"), mimeEmail},
+		{"text", []byte("Jane Doe\nBackend engineer\n"), mimeText},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03}, mimeOctet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffMime(tt.data); got != tt.want {
+				t.Errorf("sniffMime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMime(t *testing.T) {
+	markdown, err := os.ReadFile(filepath.Join("testdata", "samples", "resume.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		declared     string
+		data         []byte
+		wantMime     string
+		wantDetected string
+	}{
+		{"sniffed type wins", mimePDF, zipWith(t, map[string]string{"cv.pdf": "%PDF-1.4"}), mimeZip, mimeZip},
+		{"alias", "application/x-pdf; name=cv.pdf", []byte{0x00, 0x01}, mimePDF, mimeOctet},
+		{"declared text format kept", "text/markdown", markdown, mimeMarkdown, mimeText},
+		{"declared binary type on text", mimeDocx, []byte("plain text"), mimeText, mimeText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mime, detected := resolveMime(tt.declared, tt.data)
+			if mime != tt.wantMime || detected != tt.wantDetected {
+				t.Errorf("resolveMime = (%q, %q), want (%q, %q)", mime, detected, tt.wantMime, tt.wantDetected)
+			}
+		})
+	}
+}
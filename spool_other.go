@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads a spooled download back into memory where mmap isn't
+// wired up.
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
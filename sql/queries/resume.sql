@@ -0,0 +1,2 @@
+-- name: GetResumesBySession :many
+SELECT id, original_filename, mime, size_bytes, storage_provider, object_key, storage_url, upload_status, created_at, session_id FROM resumes WHERE session_id=$1;
@@ -0,0 +1,8 @@
+-- name: UpsertResumeProfile :exec
+INSERT INTO resume_profiles (
+resume_id, session_id, profile)
+VALUES ( $1, $2, $3)
+ON CONFLICT (resume_id)
+DO UPDATE SET
+    profile = EXCLUDED.profile,
+    updated_at = CURRENT_TIMESTAMP;
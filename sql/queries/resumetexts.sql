@@ -0,0 +1,17 @@
+-- name: GetResumeText :one
+SELECT resume_id, session_id, checksum, text, extractor, extractor_version, warnings, extraction, created_at, updated_at FROM resume_texts
+WHERE resume_id = $1;
+
+-- name: UpsertResumeText :exec
+INSERT INTO resume_texts (
+resume_id, session_id, checksum, text, extractor, extractor_version, warnings, extraction)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (resume_id)
+DO UPDATE SET
+    checksum = EXCLUDED.checksum,
+    text = EXCLUDED.text,
+    extractor = EXCLUDED.extractor,
+    extractor_version = EXCLUDED.extractor_version,
+    warnings = EXCLUDED.warnings,
+    extraction = EXCLUDED.extraction,
+    updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,13 @@
+-- name: GetSession :one
+SELECT id, created_at, name, user_id, status, job_title, job_description, COALESCE(knockout_rules, '[]')::jsonb AS knockout_rules, consistency_samples FROM sessions
+WHERE id = $1;
+
+-- name: UpdateSessionStatus :exec
+UPDATE sessions 
+SET status=$1
+WHERE id=$2;
+
+-- name: UpdateSessionReport :exec
+UPDATE sessions
+SET report_html_key=$1, report_pdf_key=$2
+WHERE id=$3;
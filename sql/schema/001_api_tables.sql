@@ -0,0 +1,38 @@
+-- sessions, resumes and analyses_results are owned by the API. The columns
+-- the worker reads and writes are mirrored here so sqlc can check its
+-- queries; IF NOT EXISTS leaves the API's tables alone.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    job_title TEXT NOT NULL,
+    job_description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS resumes (
+    id UUID PRIMARY KEY,
+    original_filename TEXT NOT NULL,
+    mime TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_provider TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    storage_url TEXT NOT NULL,
+    upload_status TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS analyses_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    results JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    session_id UUID NOT NULL UNIQUE REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+-- the API's tables are never dropped from here
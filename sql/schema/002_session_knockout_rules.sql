@@ -0,0 +1,8 @@
+-- knockout rules are set by the API when the session is created, as a JSON
+-- array of rules (KnockoutRule in knockout.go)
+
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN knockout_rules JSONB NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN knockout_rules;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE resume_profiles (
+    resume_id UUID PRIMARY KEY REFERENCES resumes(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    profile JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX resume_profiles_session_id_idx ON resume_profiles(session_id);
+
+-- +goose Down
+DROP TABLE resume_profiles;
@@ -0,0 +1,11 @@
+-- storage keys of the rendered session report; empty until one is uploaded
+
+-- +goose Up
+ALTER TABLE sessions
+    ADD COLUMN report_html_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN report_pdf_key TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE sessions
+    DROP COLUMN report_html_key,
+    DROP COLUMN report_pdf_key;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE resume_texts (
+    resume_id UUID PRIMARY KEY REFERENCES resumes(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    -- hex SHA-256 of the file the text was extracted from
+    checksum TEXT NOT NULL,
+    text TEXT NOT NULL,
+    extractor TEXT NOT NULL,
+    extractor_version TEXT NOT NULL,
+    warnings JSONB NOT NULL DEFAULT '[]',
+    extraction JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX resume_texts_session_id_idx ON resume_texts(session_id);
+
+-- +goose Down
+DROP TABLE resume_texts;
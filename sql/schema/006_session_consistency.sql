@@ -0,0 +1,9 @@
+-- self-consistency sample count for the session: 0 uses the worker's
+-- CONSISTENCY_SAMPLES, 1 evaluates each resume once, more opts in to
+-- multi-sample scoring
+
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN consistency_samples INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN consistency_samples;
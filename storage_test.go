@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/muhammadolammi/jobmatchworker/internal/database"
+)
+
+func TestHTTPStoreAllowedHosts(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret")
+	}))
+	defer internal.Close()
+	// the same server under a name that isn't allowed
+	internalURL, _ := url.Parse(internal.URL)
+	hidden := "http://localhost:" + internalURL.Port()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/resume.pdf":
+			io.WriteString(w, "resume")
+		case "/redirect":
+			http.Redirect(w, r, hidden+"/", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer public.Close()
+	publicURL, _ := url.Parse(public.URL)
+
+	tests := []struct {
+		name    string
+		allowed []string
+		url     string
+		want    string
+	}{
+		{"allowed host", []string{publicURL.Hostname()}, public.URL + "/resume.pdf", "resume"},
+		{"empty allowlist", nil, public.URL + "/resume.pdf", ""},
+		{"host not allowed", []string{publicURL.Hostname()}, hidden + "/", ""},
+		{"redirect to host not allowed", []string{publicURL.Hostname()}, public.URL + "/redirect", ""},
+		{"redirect loop", []string{publicURL.Hostname()}, public.URL + "/loop", ""},
+		{"non http scheme", []string{publicURL.Hostname()}, "file:///etc/passwd", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewHTTPStore(5*time.Second, tt.allowed)
+			body, _, err := store.Open(context.Background(), database.Resume{StorageUrl: tt.url})
+			if tt.want == "" {
+				var permanent permanentError
+				if !errors.As(err, &permanent) {
+					if body != nil {
+						body.Close()
+					}
+					t.Fatalf("Open(%s) error = %v, want a permanent error", tt.url, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open(%s) error = %v", tt.url, err)
+			}
+			defer body.Close()
+			got, _ := io.ReadAll(body)
+			if string(got) != tt.want {
+				t.Errorf("Open(%s) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3StoreAllowedBuckets(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			io.WriteString(w, "resume")
+		case http.MethodPut:
+			io.WriteString(w, `<CopyObjectResult><ETag>"x"</ETag></CopyObjectResult>`)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	cfg := aws.Config{Region: "us-east-1", Credentials: credentials.NewStaticCredentialsProvider("key", "secret", "")}
+	store := NewS3Store(cfg, server.URL, "resumes", true, []string{" partner-uploads "})
+
+	tests := []struct {
+		name       string
+		resume     database.Resume
+		allowed    bool
+		open       string
+		quarantine []string
+	}{
+		{"own bucket", database.Resume{ObjectKey: "a/cv.pdf"}, true,
+			"GET /resumes/a/cv.pdf", []string{"PUT /resumes/quarantine/a/cv.pdf", "DELETE /resumes/a/cv.pdf"}},
+		{"allowed bucket", database.Resume{ObjectKey: "ignored", StorageUrl: "s3://partner-uploads/b/cv.pdf"}, true,
+			"GET /partner-uploads/b/cv.pdf", []string{"PUT /partner-uploads/quarantine/b/cv.pdf", "DELETE /partner-uploads/b/cv.pdf"}},
+		{"other bucket", database.Resume{ObjectKey: "a/cv.pdf", StorageUrl: "s3://billing/invoices.csv"}, false, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			body, _, err := store.Open(context.Background(), tt.resume)
+			if !tt.allowed {
+				var permanent permanentError
+				if !errors.As(err, &permanent) {
+					t.Errorf("Open() error = %v, want a permanent error", err)
+				}
+				if _, err := store.(Quarantiner).Quarantine(context.Background(), tt.resume); !errors.As(err, &permanent) {
+					t.Errorf("Quarantine() error = %v, want a permanent error", err)
+				}
+				if len(requests) > 0 {
+					t.Errorf("requests sent for a bucket that isn't allowed: %q", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			body.Close()
+			if _, err := store.(Quarantiner).Quarantine(context.Background(), tt.resume); err != nil {
+				t.Fatalf("Quarantine() error = %v", err)
+			}
+			want := append([]string{tt.open}, tt.quarantine...)
+			if !slices.Equal(requests, want) {
+				t.Errorf("requests = %q, want %q", requests, want)
+			}
+		})
+	}
+}